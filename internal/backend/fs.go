@@ -1,10 +1,14 @@
 package backend
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
 
 	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/logging"
 )
 
 // https://github.com/uber-go/guide/blob/master/style.md#verify-interface-compliance
@@ -13,10 +17,16 @@ var _ domain.Backend = (*fs)(nil)
 type fs struct {
 	location  string
 	formatter domain.Formatter
+	logger    logging.Logger
 }
 
-func NewFS(location string, format domain.Formatter) *fs {
-	return &fs{location, format}
+// NewFS returns a backend saving the files formatted by format in location.
+// It logs the skipped data to logger, or to stderr if logger is nil.
+func NewFS(location string, format domain.Formatter, logger logging.Logger) *fs {
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return &fs{location, format, logger}
 }
 
 func (f *fs) Create() error {
@@ -28,13 +38,72 @@ func (f *fs) Location() string {
 	return f.location
 }
 
+// Save formats the data into a temporary file and renames it once complete,
+// so that an interrupted backup never leaves a truncated file behind.
+// The data the formatter doesn't support is skipped.
 func (f *fs) Save(data domain.Serializable) error {
+	if selective, ok := f.formatter.(domain.Selective); ok && !selective.Supports(data) {
+		f.logger.Debug("%s is skipped: the format doesn't support it", data.Slug())
+		return nil
+	}
+
 	p := path.Join(f.location, data.Slug()+f.formatter.Ext())
 
-	fd, err := os.Create(p)
+	return WriteFile(p, func(w io.Writer) error {
+		return f.formatter.Format(data, w)
+	})
+}
+
+// WriteFile writes a temporary file next to p and renames it once write
+// succeeded, so that an interruption never leaves a truncated file behind.
+// The parent directories of p are created if needed.
+func WriteFile(p string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+
+	fd, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fd.Name())
+
+	err = write(fd)
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(fd.Name(), p)
+}
+
+func (f *fs) Load(data domain.Serializable) error {
+	parser, ok := f.formatter.(domain.Parser)
+	if !ok {
+		return fmt.Errorf("the %s format can't be read back", f.formatter.Ext())
+	}
+
+	fd, err := os.Open(path.Join(f.location, data.Slug()+f.formatter.Ext()))
 	if err != nil {
 		return err
 	}
+	defer fd.Close()
+
+	return parser.Parse(data, fd)
+}
 
-	return f.formatter.Format(data, fd)
+func (f *fs) HasAsset(name string) bool {
+	_, err := os.Stat(filepath.Join(f.location, filepath.FromSlash(name)))
+	return err == nil
+}
+
+// SaveAsset writes the asset atomically, like Save
+func (f *fs) SaveAsset(name string, reader io.Reader) error {
+	p := filepath.Join(f.location, filepath.FromSlash(name))
+	return WriteFile(p, func(w io.Writer) error {
+		_, err := io.Copy(w, reader)
+		return err
+	})
 }
@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/format"
+)
+
+type failingFormatter struct{}
+
+func (f *failingFormatter) Ext() string {
+	return ".json"
+}
+
+func (f *failingFormatter) Format(data domain.Serializable, writer io.Writer) error {
+	writer.Write([]byte("[{"))
+	return errors.New("interrupted")
+}
+
+func TestSaveKeepsCompleteFiles(t *testing.T) {
+	location := t.TempDir()
+	collection := domain.NewCollection([]*domain.Entry{{ID: "1", Title: "Munich"}}, "films", "done", "mlcdf")
+
+	if err := NewFS(location, format.NewJSON(false), nil).Save(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := os.ReadFile(filepath.Join(location, "films-done.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewFS(location, &failingFormatter{}, nil).Save(collection); err == nil {
+		t.Fatalf("expected the save to fail")
+	}
+
+	content, err := os.ReadFile(filepath.Join(location, "films-done.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(saved) {
+		t.Errorf("a failed save should not overwrite the previous file, got %s", content)
+	}
+
+	files, err := os.ReadDir(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected no temporary file left behind, got %d files", len(files))
+	}
+}
+
+// recordingLogger keeps the debug messages
+type recordingLogger struct {
+	debug []string
+}
+
+func (l *recordingLogger) Info(format string, v ...interface{}) {}
+
+func (l *recordingLogger) Debug(format string, v ...interface{}) {
+	l.debug = append(l.debug, fmt.Sprintf(format, v...))
+}
+
+func TestSaveSkipsUnsupportedData(t *testing.T) {
+	location := t.TempDir()
+	logger := &recordingLogger{}
+	back := NewFS(location, format.NewLetterboxd(), logger)
+
+	for _, data := range []domain.Serializable{
+		domain.NewCollection(nil, "films", "done", "mlcdf"),
+		domain.NewCollection(nil, "series", "done", "mlcdf"),
+		domain.NewDiary(nil, "mlcdf"),
+	} {
+		if err := back.Save(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := os.ReadDir(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "films-done.csv" {
+		t.Errorf("expected films-done.csv only, got %v", files)
+	}
+	if len(logger.debug) != 2 {
+		t.Errorf("expected the 2 skipped files to be logged, got %v", logger.debug)
+	}
+}
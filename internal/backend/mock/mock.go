@@ -2,18 +2,30 @@
 
 package mock
 
-import "go.mlcdf.fr/sc-backup/internal/domain"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
 
 var _ domain.Backend = (*Backend)(nil)
 
 // Backend is used for testing purpose
 type Backend struct {
-	Data map[string]interface{}
+	Data   map[string]interface{}
+	Assets map[string][]byte
+
+	mu sync.Mutex
 }
 
 func NewBackend() *Backend {
 	return &Backend{
-		Data: map[string]interface{}{},
+		Data:   map[string]interface{}{},
+		Assets: map[string][]byte{},
 	}
 }
 
@@ -29,3 +41,34 @@ func (m *Backend) Save(data domain.Serializable) error {
 	m.Data[data.Slug()] = data
 	return nil
 }
+
+func (m *Backend) Load(data domain.Serializable) error {
+	saved, ok := m.Data[data.Slug()].(domain.Serializable)
+	if !ok {
+		return fmt.Errorf("%s: %w", data.Slug(), os.ErrNotExist)
+	}
+
+	content, err := json.Marshal(saved.JSON())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, data.JSON())
+}
+
+func (m *Backend) HasAsset(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.Assets[name]
+	return ok
+}
+
+func (m *Backend) SaveAsset(name string, reader io.Reader) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Assets[name] = content
+	return nil
+}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+var _ domain.AssetSource = (*Client)(nil)
+
+// Asset downloads an asset, retrying like the pages
+func (c *Client) Asset(ctx context.Context, url string) (io.ReadCloser, error) {
+	res, err := c.request(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		res.Body.Close()
+		return nil, fmt.Errorf("error: http %d for url %s", res.StatusCode, url)
+	}
+	return res.Body, nil
+}
+
+// Covers stores the posters of the entries in a single backend, usually at
+// the root of the output directory, so that a poster shared by several
+// collections and lists is only downloaded once
+type Covers struct {
+	back        domain.Backend
+	concurrency int
+
+	mu sync.Mutex
+	// saved are the names of the covers known to be in back
+	saved map[string]bool
+}
+
+// NewCovers returns Covers stored in back, downloading concurrency posters
+// at once
+func NewCovers(back domain.Backend, concurrency int) *Covers {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Covers{back: back, concurrency: concurrency, saved: map[string]bool{}}
+}
+
+// has tells whether the cover is in the backend, either downloaded during
+// this backup or by a previous one
+func (c *Covers) has(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.saved[name] {
+		return true
+	}
+	if c.back.HasAsset(name) {
+		c.saved[name] = true
+		return true
+	}
+	return false
+}
+
+// save downloads a cover into the backend
+func (c *Covers) save(ctx context.Context, src domain.AssetSource, name string, url string) error {
+	asset, err := src.Asset(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer asset.Close()
+
+	if err := c.back.SaveAsset(name, asset); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.saved[name] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// path returns the path of a cover relative to location, the backend of the
+// data referencing it
+func (c *Covers) path(name string, location string) string {
+	rel, err := filepath.Rel(location, c.back.Location())
+	if err != nil {
+		return path.Join(filepath.ToSlash(c.back.Location()), name)
+	}
+	return path.Join(filepath.ToSlash(rel), name)
+}
+
+// coverName returns the name of the cover of an entry in a backend
+func coverName(entry *domain.Entry) string {
+	return path.Join("covers", entry.ID+".jpg")
+}
+
+// coverURL returns the URL of the poster of an entry, if any
+func coverURL(entry *domain.Entry) string {
+	if entry.Poster == "" && entry.Details != nil {
+		return entry.Details.CoverURL
+	}
+	return entry.Poster
+}
+
+// saveCovers downloads the posters of the entries that are not in covers
+// yet, and sets the PosterPath of the entries whose poster is in covers,
+// relative to location. It does nothing if src or covers is nil.
+func saveCovers(ctx context.Context, src domain.AssetSource, entries []*domain.Entry, covers *Covers, location string) error {
+	if src == nil || covers == nil {
+		return nil
+	}
+
+	tasks := []*pool.Task{}
+	queued := map[string]bool{}
+
+	for _, entry := range entries {
+		name, url := coverName(entry), coverURL(entry)
+		if entry.ID == "" || url == "" || queued[name] || covers.has(name) {
+			continue
+		}
+		queued[name] = true
+
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			return nil, covers.save(ctx, src, name, url)
+		}))
+	}
+
+	p := pool.NewPool(tasks, covers.concurrency)
+	p.Run(ctx)
+
+	for _, entry := range entries {
+		if name := coverName(entry); entry.ID != "" && covers.has(name) {
+			entry.PosterPath = covers.path(name, location)
+		}
+	}
+	return p.Err()
+}
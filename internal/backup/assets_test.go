@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/backend"
+	"go.mlcdf.fr/sc-backup/internal/backend/mock"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+func TestSaveCovers(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/munich.jpg", "/heat.jpg":
+			w.Write([]byte("jpeg " + r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	back := mock.NewBackend()
+	back.Assets["covers/3.jpg"] = []byte("already downloaded")
+
+	entries := []*domain.Entry{
+		{ID: "1", Poster: server.URL + "/munich.jpg"},
+		{ID: "1", Poster: server.URL + "/munich.jpg"},
+		{ID: "2", Details: &domain.Product{CoverURL: server.URL + "/heat.jpg"}},
+		{ID: "3", Poster: server.URL + "/skipped.jpg"},
+		{ID: "4", Poster: server.URL + "/missing.jpg"},
+		{ID: "5"},
+	}
+
+	if err := saveCovers(context.Background(), client, entries, NewCovers(back, DefaultConcurrency), back.Location()); err == nil {
+		t.Errorf("expected an error for the missing poster")
+	}
+
+	if n := requests["/munich.jpg"]; n != 1 {
+		t.Errorf("expected the poster shared by 2 entries to be downloaded once, got %d", n)
+	}
+	if n := requests["/skipped.jpg"]; n != 0 {
+		t.Errorf("expected the poster already downloaded to be skipped, got %d requests", n)
+	}
+	if content := string(back.Assets["covers/2.jpg"]); content != "jpeg /heat.jpg" {
+		t.Errorf("unexpected content %q for the cover of the product page", content)
+	}
+
+	expected := []string{"covers/1.jpg", "covers/1.jpg", "covers/2.jpg", "covers/3.jpg", "", ""}
+	for i, entry := range entries {
+		if entry.PosterPath != expected[i] {
+			t.Errorf("entry %d: expected poster path %q, got %q", i, expected[i], entry.PosterPath)
+		}
+	}
+}
+
+func TestSaveCoversWithoutSource(t *testing.T) {
+	entries := []*domain.Entry{{ID: "1", Poster: "https://example.com/munich.jpg"}}
+	back := mock.NewBackend()
+	if err := saveCovers(context.Background(), nil, entries, NewCovers(back, DefaultConcurrency), back.Location()); err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].PosterPath != "" {
+		t.Errorf("expected no poster path without --images, got %q", entries[0].PosterPath)
+	}
+}
+
+func TestSaveCoversShared(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("jpeg"))
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	output := t.TempDir()
+	covers := NewCovers(backend.NewFS(output, nil, nil), DefaultConcurrency)
+
+	done := []*domain.Entry{{ID: "1", Poster: server.URL + "/munich.jpg"}}
+	if err := saveCovers(context.Background(), client, done, covers, filepath.Join(output, "mlcdf")); err != nil {
+		t.Fatal(err)
+	}
+
+	list := []*domain.Entry{{ID: "1", Poster: server.URL + "/munich.jpg"}}
+	if err := saveCovers(context.Background(), client, list, covers, filepath.Join(output, "mlcdf", "lists")); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected the poster shared by a collection and a list to be downloaded once, got %d requests", requests)
+	}
+	if _, err := os.Stat(filepath.Join(output, "covers", "1.jpg")); err != nil {
+		t.Errorf("expected the cover in the output directory: %s", err)
+	}
+	if p := done[0].PosterPath; p != "../covers/1.jpg" {
+		t.Errorf("unexpected poster path %q for the collection", p)
+	}
+	if p := list[0].PosterPath; p != "../../covers/1.jpg" {
+		t.Errorf("unexpected poster path %q for the list", p)
+	}
+}
@@ -1,54 +1,115 @@
 package backup
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math"
-	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/cache"
+	"go.mlcdf.fr/sc-backup/internal/checkpoint"
 	"go.mlcdf.fr/sc-backup/internal/domain"
 	"go.mlcdf.fr/sc-backup/internal/logging"
 	"go.mlcdf.fr/sc-backup/internal/pool"
 )
 
-const URL = "https://old.senscritique.com"
+// Categories and Filters are the collections known to the backup
+var Categories = []string{"films", "series", "bd", "livres", "albums", "morceaux", "jeuxvideo"}
+var Filters = []string{domain.StateDone, domain.StateWish, domain.StateInProgress}
+
+// DefaultFilters are the filters backed up unless others are selected. The
+// in progress collections are left out as the legacy website can't back
+// them up.
+var DefaultFilters = []string{domain.StateDone, domain.StateWish}
+
+// collectionFilters maps the filters to their segment in the collection URLs
+// of the legacy website, which is also the data-sc-collection-filter of
+// their counter. The URL of the in progress collections is not known.
+var collectionFilters = map[string]string{
+	domain.StateDone: "done",
+	domain.StateWish: "wish",
+}
 
-var Categories = []string{"films", "series", "bd", "livres", "albums", "morceaux"}
-var Filters = []string{"done", "wish"}
+// inProgressCategories are the categories whose products can be in progress
+var inProgressCategories = []string{"series", "bd", "livres", "jeuxvideo"}
 
-type parseFunc func(document *goquery.Document) ([]*domain.Entry, error)
+// HasCollection tells whether category has a collection for filter
+func HasCollection(category string, filter string) bool {
+	if filter == domain.StateInProgress {
+		return contains(inProgressCategories, category)
+	}
+	return contains(Categories, category) && contains(Filters, filter)
+}
 
-var client = &http.Client{
-	Timeout: time.Second * 20,
-	CheckRedirect: func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	},
+// ParseCategories parses a comma-separated list of categories. An empty
+// value selects all the Categories.
+func ParseCategories(value string) ([]string, error) {
+	return parseChoices(value, Categories, Categories, "category")
 }
 
-func request(url string) (*http.Response, error) {
-	logging.Debug("GET %s", url)
-	res, err := client.Get(url)
+// ParseFilters parses a comma-separated list of filters. An empty value
+// selects the DefaultFilters.
+func ParseFilters(value string) ([]string, error) {
+	return parseChoices(value, Filters, DefaultFilters, "filter")
+}
 
-	// check for response error
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to GET %s", url)
+// parseChoices returns the known values selected in value, in the order of
+// known, or defaults if none is selected
+func parseChoices(value string, known []string, defaults []string, kind string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, choice := range strings.Split(value, ",") {
+		choice = strings.ToLower(strings.TrimSpace(choice))
+		if choice == "" {
+			continue
+		}
+		if !contains(known, choice) {
+			return nil, fmt.Errorf("unknown %s %s: it should be one of %s", kind, choice, strings.Join(known, ", "))
+		}
+		selected[choice] = true
+	}
+
+	if len(selected) == 0 {
+		return defaults, nil
+	}
+
+	choices := make([]string, 0, len(selected))
+	for _, choice := range known {
+		if selected[choice] {
+			choices = append(choices, choice)
+		}
 	}
+	return choices, nil
+}
 
-	if res.StatusCode > 400 {
-		return nil, fmt.Errorf("error: http %d for url %s", res.StatusCode, res.Request.URL)
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
+}
+
+type parseFunc func(document *goquery.Document) ([]*domain.Entry, error)
 
-	return res, nil
+func (c *Client) makeCollectionURL(username string, category string, segment string) string {
+	return fmt.Sprintf("%s/%s/collection/%s/%s/all/all/all/all/all/all/all/page-", c.baseURL, username, segment, category)
 }
 
-func makeCollectionURL(username string, category string, filter string) string {
-	return fmt.Sprintf("%s/%s/collection/%s/%s/all/all/all/all/all/all/all/page-", URL, username, filter, category)
+// collectionSegment returns the URL segment of filter, or an error if the
+// legacy website has no collection for it
+func collectionSegment(filter string) (string, error) {
+	segment, ok := collectionFilters[filter]
+	if !ok {
+		return "", fmt.Errorf("the %s collections can't be backed up from the legacy website", filter)
+	}
+	return segment, nil
 }
 
 func makeListURL(url string, index int) string {
@@ -64,8 +125,8 @@ func makeListURL(url string, index int) string {
 	return url
 }
 
-func validateUser(username string) error {
-	res, err := request(URL + "/" + username)
+func (c *Client) validateUser(ctx context.Context, username string) error {
+	res, err := c.request(ctx, c.baseURL+"/"+username)
 
 	if err != nil {
 		return errors.Wrap(err, "failed to validate user")
@@ -118,8 +179,32 @@ func parseGenre(s *goquery.Selection) ([]string, error) {
 	return filterWeirdGenre(splitWord(genres)), nil
 }
 
+// parseNumber parses a number, optionally wrapped in parentheses like the
+// years and the counters
+func parseNumber(s string) (int, error) {
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	return strconv.Atoi(s)
+}
+
+// documentURL returns the URL the document was fetched from
+func documentURL(document *goquery.Document) string {
+	if document.Url == nil {
+		return ""
+	}
+	return document.Url.String()
+}
+
+// parseDocument parses the entries of a collection or list page. Fields that
+// can't be parsed are left empty and reported as ParseErrors.
 func parseDocument(document *goquery.Document) ([]*domain.Entry, error) {
 	entries := make([]*domain.Entry, 0)
+	var parseErrors ParseErrors
+	fail := func(index int, field string, raw string, err error) {
+		parseErrors = append(parseErrors, &ParseError{documentURL(document), index, field, raw, err})
+	}
+
 	document.Find(".elco-collection-item, .elli-item").Each(func(i int, s *goquery.Selection) {
 		id, _ := s.Find(".elco-collection-content > .elco-collection-poster, .elli-media figure").Attr("data-sc-product-id")
 		title := strings.TrimSpace(s.Find(".elco-title a").Text())
@@ -131,6 +216,13 @@ func parseDocument(document *goquery.Document) ([]*domain.Entry, error) {
 			OriginalTitle: originalTitle,
 		}
 
+		poster := s.Find(".elco-collection-poster img, .elli-media figure img").First()
+		if src, exists := poster.Attr("data-original"); exists {
+			entry.Poster = resolveURL(document, src)
+		} else if src, exists := poster.Attr("src"); exists {
+			entry.Poster = resolveURL(document, src)
+		}
+
 		entry.Authors = make([]string, 0, 5)
 		s.Find(".elco-product-detail a.elco-baseline-a, .elli-content a.elco-baseline-a").Each(func(i int, s *goquery.Selection) {
 			author := strings.TrimSpace(s.Text())
@@ -141,9 +233,9 @@ func parseDocument(document *goquery.Document) ([]*domain.Entry, error) {
 		// some works don't have year, for example Œdipe Roi
 		// https://www.senscritique.com/mlcdf/collection/done/livres/all/all/all/all/all/all/list/page-1
 		if parsedDate != "" {
-			year, err := strconv.Atoi(parsedDate[1 : len(parsedDate)-1])
+			year, err := parseNumber(parsedDate)
 			if err != nil {
-				log.Fatal(err)
+				fail(i, "year", parsedDate, err)
 			}
 			entry.Year = year
 		}
@@ -151,13 +243,20 @@ func parseDocument(document *goquery.Document) ([]*domain.Entry, error) {
 		var err error
 		entry.Genres, err = parseGenre(s)
 		if err != nil {
-			log.Fatal(err)
+			fail(i, "genres", s.Find("p.elco-baseline.elco-options").Text(), err)
 		}
 
 		entry.Comment = strings.TrimSpace(s.Find(".elli-annotation-content").Text())
 
 		entry.Favorite = s.Find(".eins-user-recommend").Length() != 0
 
+		// only the video games have platforms
+		s.Find(".elco-gamesystem").Each(func(i int, s *goquery.Selection) {
+			if platform := strings.TrimSpace(s.Text()); platform != "" {
+				entry.Platforms = append(entry.Platforms, platform)
+			}
+		})
+
 		var ratingString string
 		if isList(document) {
 			ratingString = strings.TrimSpace(s.Find(".elrua-useraction-inner").Text())
@@ -168,18 +267,18 @@ func parseDocument(document *goquery.Document) ([]*domain.Entry, error) {
 		if ratingString != "" {
 			rating, err := strconv.Atoi(ratingString)
 			if err != nil {
-				log.Fatal(err)
+				fail(i, "rating", ratingString, err)
 			}
 			entry.Rating = rating
 		}
 		entries = append(entries, entry)
 	})
 
-	return entries, nil
+	return entries, parseErrors.err()
 }
 
-func collectionSize(document *goquery.Document, filter string) (int, error) {
-	_nbOfEntries := strings.TrimSpace(document.Find(fmt.Sprintf("[data-sc-collection-filter=%s] span span", filter)).Text())
+func collectionSize(document *goquery.Document, segment string) (int, error) {
+	_nbOfEntries := strings.TrimSpace(document.Find(fmt.Sprintf("[data-sc-collection-filter=%s] span span", segment)).Text())
 
 	if _nbOfEntries == "" {
 		if document.Find(".elco-collection-item-empty").Length() > 0 {
@@ -221,54 +320,54 @@ func listDescription(document *goquery.Document) string {
 	return strings.TrimSpace(document.Find("[data-rel=list-description]").Text())
 }
 
-func extractPage(url string, parseF parseFunc) ([]*domain.Entry, error) {
-	res, err := request(url)
+func (c *Client) extractPage(ctx context.Context, url string, parseF parseFunc) ([]*domain.Entry, error) {
+	res, err := c.request(ctx, url)
 	if err != nil {
-		return nil, err
+		return nil, pageError(url, err)
 	}
 
 	document, err := goquery.NewDocumentFromResponse(res)
 	if err != nil {
-		return nil, err
+		return nil, pageError(url, err)
 	}
 
 	entries, err := parseF(document)
-	if err != nil {
+	if err := c.checkParse(err); err != nil {
 		return nil, err
 	}
 	return entries, nil
 }
 
-// List backs up a list
-func List(url string, back domain.Backend) error {
-	res, err := request(url)
-	if err != nil {
-		return err
-	}
+// ValidateUser returns an error if the user does not exist or has a limited profile
+func (c *Client) ValidateUser(ctx context.Context, username string) error {
+	return c.validateUser(ctx, username)
+}
 
-	err = back.Create()
+// List fetches a list
+func (c *Client) List(ctx context.Context, url string) (*domain.List, error) {
+	res, err := c.request(ctx, url)
 	if err != nil {
-		return err
+		return nil, pageError(url, err)
 	}
 
 	document, err := goquery.NewDocumentFromResponse(res)
 	if err != nil {
-		return err
+		return nil, pageError(url, err)
 	}
 
 	size, err := listSize(document)
 	if err != nil {
-		return errors.Wrapf(err, "%s", url)
+		return nil, pageError(url, errors.Wrapf(err, "%s", url))
 	}
 
 	title, err := listTitle(document)
 	if err != nil {
-		return errors.Wrapf(err, "%s", url)
+		return nil, pageError(url, errors.Wrapf(err, "%s", url))
 	}
 
 	entries, err := parseDocument(document)
-	if err != nil {
-		return err
+	if err := c.checkParse(err); err != nil {
+		return nil, err
 	}
 
 	list := domain.NewList(entries, title, listDescription(document))
@@ -280,8 +379,8 @@ func List(url string, back domain.Backend) error {
 
 		for i := 2; i <= int(nbOfPages); i++ {
 			i := i
-			tasks = append(tasks, pool.NewTask(func() (interface{}, error) {
-				entries, err := extractPage(makeListURL(url, i), parseDocument)
+			tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+				entries, err := c.extractPage(ctx, makeListURL(url, i), parseDocument)
 				if err != nil {
 					return nil, err
 				}
@@ -289,133 +388,255 @@ func List(url string, back domain.Backend) error {
 			}))
 		}
 
-		p := pool.NewPool(tasks, 20)
-		p.Run()
+		p := pool.NewPool(tasks, c.concurrency)
+		p.Run(ctx)
 
 		list.Entries, err = p.Merge(list.Entries)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if nbEntries := len(list.Entries); nbEntries != size {
-		return fmt.Errorf("the list '%s' has %d entries, but only %d were found", title, size, nbEntries)
+		return nil, fmt.Errorf("the list '%s' has %d entries, but only %d were found", title, size, nbEntries)
 	}
 
-	err = back.Save(list)
-	if err != nil {
-		return err
+	return list, nil
+}
+
+// pageTask returns a task extracting a page, unless the checkpoint already
+// holds its entries
+func (c *Client) pageTask(key string, url string, parseF parseFunc) *pool.Task {
+	return pool.NewTask(func(ctx context.Context) (interface{}, error) {
+		if entries, ok := c.checkpoint.Page(key); ok {
+			return entries, nil
+		}
+
+		entries, err := c.extractPage(ctx, url, parseF)
+		if err != nil {
+			return nil, err
+		}
+
+		c.recordPage(key, entries)
+		return entries, nil
+	})
+}
+
+// recordPage saves the entries of a page in the checkpoint
+func (c *Client) recordPage(key string, entries []*domain.Entry) {
+	if err := c.checkpoint.SetPage(key, entries); err != nil {
+		c.logger.Info("warning: failed to save the checkpoint: %s", err)
 	}
+}
 
-	return nil
+// recordSize saves the number of entries of a collection in the checkpoint
+func (c *Client) recordSize(slug string, size int) {
+	if err := c.checkpoint.SetSize(slug, size); err != nil {
+		c.logger.Info("warning: failed to save the checkpoint: %s", err)
+	}
 }
 
-// Collection backs up a user collection
-func Collection(username string, back domain.Backend) error {
-	err := validateUser(username)
+// Collection fetches a user collection for the given category and filter
+func (c *Client) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	segment, err := collectionSegment(filter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	logging.Info("Backing up collection for user %s", username)
-	back.Create()
+	url := c.makeCollectionURL(username, category, segment)
+	slug := domain.NewCollection(nil, category, filter, username).Slug()
+
+	size, hasSize := c.checkpoint.Size(slug)
+	entries, hasFirstPage := c.checkpoint.Page(checkpoint.PageKey(slug, 1))
+
+	if !hasSize || !hasFirstPage {
+		res, err := c.request(ctx, url)
+		if err != nil {
+			return nil, pageError(url, err)
+		}
+
+		document, err := goquery.NewDocumentFromResponse(res)
+		if err != nil {
+			return nil, pageError(url, err)
+		}
+
+		size, err = collectionSize(document, segment)
+		if err != nil {
+			return nil, pageError(url, errors.Wrapf(err, "%s", url))
+		}
+
+		entries, err = parseDocument(document)
+		if err := c.checkParse(err); err != nil {
+			return nil, err
+		}
+
+		c.recordSize(slug, size)
+		c.recordPage(checkpoint.PageKey(slug, 1), entries)
+	}
+
+	collection := domain.NewCollection(entries, category, filter, username)
+
+	nbOfPages := math.Ceil(float64(size) / 18)
+	if nbOfPages > 1 {
+		tasks := []*pool.Task{}
+
+		for i := 2; i <= int(nbOfPages); i++ {
+			tasks = append(tasks, c.pageTask(checkpoint.PageKey(slug, i), url+strconv.Itoa(i), parseDocument))
+		}
+
+		p := pool.NewPool(tasks, c.concurrency)
+		p.Run(ctx)
+
+		var err error
+		collection.Entries, err = p.Merge(collection.Entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return collection, nil
+}
+
+// sameEntry tells whether an entry hasn't changed since the previous backup
+func sameEntry(previous *domain.Entry, entry *domain.Entry) bool {
+	return previous.Title == entry.Title &&
+		previous.OriginalTitle == entry.OriginalTitle &&
+		previous.Year == entry.Year &&
+		previous.Rating == entry.Rating &&
+		previous.Favorite == entry.Favorite &&
+		previous.Comment == entry.Comment
+}
 
-	dates, err := journal(username)
+// CollectionSince fetches the pages of a collection, newest first, until a
+// page only holds entries of previous that haven't changed. The fetched
+// entries come first, followed by the remaining entries of previous.
+func (c *Client) CollectionSince(ctx context.Context, username string, category string, filter string, previous *domain.Collection) (*domain.Collection, error) {
+	segment, err := collectionSegment(filter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, category := range Categories {
-		for _, filter := range Filters {
+	url := c.makeCollectionURL(username, category, segment)
 
-			url := makeCollectionURL(username, category, filter)
-			res, err := request(url)
-			if err != nil {
-				return err
-			}
+	known := make(map[string]*domain.Entry, len(previous.Entries))
+	for _, entry := range previous.Entries {
+		known[entry.ID] = entry
+	}
 
-			document, err := goquery.NewDocumentFromResponse(res)
-			if err != nil {
-				return err
-			}
+	entries := make([]*domain.Entry, 0)
+	fetched := map[string]bool{}
+	size := 0
 
-			size, err := collectionSize(document, filter)
-			if err != nil {
-				return errors.Wrapf(err, "%s", url)
-			}
+	for page := 1; ; page++ {
+		pageURL := url
+		if page > 1 {
+			pageURL += strconv.Itoa(page)
+		}
+
+		res, err := c.request(ctx, pageURL)
+		if err != nil {
+			return nil, pageError(pageURL, err)
+		}
 
-			entries, err := parseDocument(document)
+		document, err := goquery.NewDocumentFromResponse(res)
+		if err != nil {
+			return nil, pageError(pageURL, err)
+		}
+
+		if page == 1 {
+			size, err = collectionSize(document, segment)
 			if err != nil {
-				return err
+				return nil, pageError(pageURL, errors.Wrapf(err, "%s", pageURL))
 			}
+		}
 
-			collection := domain.NewCollection(entries, category, filter, username)
-
-			nbOfPages := math.Ceil(float64(size) / 18)
-			if nbOfPages > 1 {
-				tasks := []*pool.Task{}
-
-				for i := 2; i <= int(nbOfPages); i++ {
-					i := i
-					tasks = append(tasks, pool.NewTask(func() (interface{}, error) {
-						entries, err := extractPage(url+strconv.Itoa(i), parseDocument)
-						if err != nil {
-							return nil, err
-						}
-						return entries, nil
-					}))
-				}
-
-				p := pool.NewPool(tasks, 20)
-				p.Run()
+		pageEntries, err := parseDocument(document)
+		if err := c.checkParse(err); err != nil {
+			return nil, err
+		}
 
-				collection.Entries, err = p.Merge(collection.Entries)
-				if err != nil {
-					return err
-				}
+		unchanged := 0
+		for _, entry := range pageEntries {
+			if previousEntry, ok := known[entry.ID]; ok && sameEntry(previousEntry, entry) {
+				unchanged++
 			}
+			fetched[entry.ID] = true
+			entries = append(entries, entry)
+		}
 
-			if filter == "done" {
-				for _, entry := range collection.Entries {
-					for _, d := range dates {
-						if entry.ID == d.ID {
-							entry.DoneDate = d.DoneDate
-						}
-					}
-				}
-			}
+		if unchanged == len(pageEntries) || page*18 >= size {
+			c.logger.Debug("%s-%s: %d new or updated entries in %d pages", category, filter, len(entries)-unchanged, page)
+			break
+		}
+	}
 
-			err = back.Save(collection)
-			if err != nil {
-				return err
-			}
+	for _, entry := range previous.Entries {
+		if !fetched[entry.ID] {
+			entries = append(entries, entry)
 		}
 	}
 
-	return nil
+	if len(entries) != size {
+		return nil, errors.Wrapf(domain.ErrFullBackupNeeded, "%s-%s has %d entries, but the merged backup has %d", category, filter, size, len(entries))
+	}
+
+	collection := domain.NewCollection(entries, category, filter, username)
+	collection.FullBackupAt = previous.FullBackupAt
+	return collection, nil
 }
 
-// journal parse a user journal and extract done dates
-func journal(username string) ([]*domain.Entry, error) {
-	url := URL + "/" + username + "/journal/all/all"
-	res, err := request(url)
-	if err != nil {
-		return nil, err
+// Journal parses a user journal and extracts done dates. The journal is
+// fetched once per category, unless all the Categories are requested.
+func (c *Client) Journal(ctx context.Context, username string, categories []string) ([]*domain.Entry, error) {
+	if len(categories) == 0 || len(categories) == len(Categories) {
+		return c.journal(ctx, username, "all")
 	}
 
-	document, err := goquery.NewDocumentFromResponse(res)
-	if err != nil {
-		return nil, err
+	entries := make([]*domain.Entry, 0)
+	for _, category := range categories {
+		categoryEntries, err := c.journal(ctx, username, category)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, categoryEntries...)
 	}
+	return entries, nil
+}
 
-	size, err := journalSize(document)
-	if err != nil {
-		return nil, err
+// journal parses the journal of a single category, or of all of them
+func (c *Client) journal(ctx context.Context, username string, category string) ([]*domain.Entry, error) {
+	url := c.baseURL + "/" + username + "/journal/" + category + "/all"
+	slug := "journal"
+	if category != "all" {
+		slug += "-" + category
 	}
 
-	entries, err := extractDoneDate(document)
-	if err != nil {
-		return nil, err
+	size, hasSize := c.checkpoint.Size(slug)
+	entries, hasFirstPage := c.checkpoint.Page(checkpoint.PageKey(slug, 1))
+
+	if !hasSize || !hasFirstPage {
+		res, err := c.request(ctx, url)
+		if err != nil {
+			return nil, pageError(url, err)
+		}
+
+		document, err := goquery.NewDocumentFromResponse(res)
+		if err != nil {
+			return nil, pageError(url, err)
+		}
+
+		size, err = journalSize(document)
+		if err := c.checkParse(err); err != nil {
+			return nil, err
+		}
+
+		entries, err = extractDoneDate(document)
+		if err := c.checkParse(err); err != nil {
+			return nil, err
+		}
+
+		c.recordSize(slug, size)
+		c.recordPage(checkpoint.PageKey(slug, 1), entries)
 	}
 
 	nbOfPages := math.Ceil(float64(size) / 20)
@@ -423,19 +644,14 @@ func journal(username string) ([]*domain.Entry, error) {
 		tasks := []*pool.Task{}
 
 		for i := 2; i <= int(nbOfPages); i++ {
-			i := i
-			tasks = append(tasks, pool.NewTask(func() (interface{}, error) {
-				entries, err := extractPage(URL+"/"+username+"/journal/all/all/all/page-"+strconv.Itoa(i)+".ajax", extractDoneDate)
-				if err != nil {
-					return nil, err
-				}
-				return entries, nil
-			}))
+			pageURL := c.baseURL + "/" + username + "/journal/" + category + "/all/all/page-" + strconv.Itoa(i) + ".ajax"
+			tasks = append(tasks, c.pageTask(checkpoint.PageKey(slug, i), pageURL, extractDoneDate))
 		}
 
-		p := pool.NewPool(tasks, 20)
-		p.Run()
+		p := pool.NewPool(tasks, c.concurrency)
+		p.Run(ctx)
 
+		var err error
 		entries, err = p.Merge(entries)
 		if err != nil {
 			return nil, err
@@ -447,20 +663,37 @@ func journal(username string) ([]*domain.Entry, error) {
 
 func extractDoneDate(document *goquery.Document) ([]*domain.Entry, error) {
 	entries := make([]*domain.Entry, 0)
+	var parseErrors ParseErrors
 
-	document.Find(".eldi-list-item").Each(func(i int, s *goquery.Selection) {
-		date, exists := s.Attr("data-sc-datedone")
+	document.Find(".eldi-list-item").Each(func(index int, s *goquery.Selection) {
+		rawDate, exists := s.Attr("data-sc-datedone")
 		if !exists {
 			// ce n'est pas une oeuvre, mais un titre année ou mois
 			// on les ignore
 			return
 		}
 
-		s.Find(".eldi-collection-container").Each(func(i int, s *goquery.Selection) {
+		var date *domain.PartialDate
+		if parsed, err := domain.ParsePartialDate(rawDate); err != nil {
+			parseErrors = append(parseErrors, &ParseError{documentURL(document), index, "done date", rawDate, err})
+		} else if !parsed.IsZero() {
+			date = &parsed
+		}
+
+		s.Find(".eldi-collection-container").Each(func(_ int, s *goquery.Selection) {
 			parsedId, exists := s.Find(".eldi-collection-poster").Attr("data-sc-product-id")
 			if !exists {
-				// pour les épisodes de série, on arrive ici par exemple.
-				// on les ignore
+				// pour les épisodes de série, on arrive ici
+				episode, err := parseEpisode(s, date)
+				if err != nil {
+					parseErrors = append(parseErrors, &ParseError{documentURL(document), index, "episode number", s.Find(".eldi-collection-episode-number").Text(), err})
+				}
+				if episode != nil {
+					entries = append(entries, &domain.Entry{
+						ID:       episode.SeriesID,
+						Episodes: []*domain.Episode{episode},
+					})
+				}
 				return
 			}
 			id := strings.TrimSpace(parsedId)
@@ -471,20 +704,597 @@ func extractDoneDate(document *goquery.Document) ([]*domain.Entry, error) {
 			entries = append(entries, e)
 		})
 	})
-	return entries, nil
+	return entries, parseErrors.err()
+}
+
+var episodeNumber = regexp.MustCompile(`(?i)(?:saison|s)\s*(\d+)\D*?(?:épisode|e)\s*(\d+)`)
+
+// parseEpisode parses an episode of the journal. It returns nil if s is not
+// an episode. If the number can't be parsed, the episode is returned without
+// its season and number, along with the error.
+func parseEpisode(s *goquery.Selection, date *domain.PartialDate) (*domain.Episode, error) {
+	episode := s.Find(".eldi-collection-episode")
+	seriesID, exists := episode.Attr("data-sc-product-id")
+	if !exists {
+		return nil, nil
+	}
+
+	id, _ := episode.Attr("data-sc-episode-id")
+	result := &domain.Episode{
+		ID:          strings.TrimSpace(id),
+		SeriesID:    strings.TrimSpace(seriesID),
+		WatchedDate: date,
+	}
+
+	number := strings.TrimSpace(episode.Find(".eldi-collection-episode-number").Text())
+	matches := episodeNumber.FindStringSubmatch(number)
+	if matches == nil {
+		return result, fmt.Errorf("no season and episode number")
+	}
+	result.Season, _ = strconv.Atoi(matches[1])
+	result.Number, _ = strconv.Atoi(matches[2])
+	return result, nil
 }
 
+// journalSize sums the counters of the journal. Counters that can't be
+// parsed are skipped and reported as ParseErrors.
 func journalSize(document *goquery.Document) (int, error) {
 	size := 0
+	var parseErrors ParseErrors
 	document.Find(".elco-collection-count").Each(func(i int, s *goquery.Selection) {
 		parsedValue := strings.TrimSpace(s.Text())
 		if parsedValue != "" {
-			nb, err := strconv.Atoi(parsedValue[1 : len(parsedValue)-1])
+			nb, err := parseNumber(parsedValue)
 			if err != nil {
-				log.Fatal(err)
+				parseErrors = append(parseErrors, &ParseError{documentURL(document), -1, "journal size", parsedValue, err})
+				return
 			}
 			size += nb
 		}
 	})
-	return size, nil
+	return size, parseErrors.err()
+}
+
+// ErrIncomplete is returned when some collections could not be backed up
+// but the others were saved
+var ErrIncomplete = errors.New("some collections could not be backed up")
+
+// Options configures a collection backup
+type Options struct {
+	// KeepGoing saves every collection that could be backed up instead of
+	// stopping at the first failure. The failures are listed in the Summary.
+	KeepGoing bool
+
+	// Incremental updates the previous backup of each collection with its
+	// newest entries, if the source is a domain.IncrementalSource
+	Incremental bool
+
+	// FullEvery forces a full backup of the collections whose last full
+	// backup is older, to pick up the deleted entries
+	FullEvery time.Duration
+
+	// Categories and Filters select the collections to back up. They
+	// default to all the Categories and to the DefaultFilters.
+	Categories []string
+	Filters    []string
+
+	// Reviews also backs up the reviews written by the user, if the source
+	// is a domain.ReviewSource
+	Reviews bool
+
+	// Social also backs up the scouts and the followers of the user, if the
+	// source is a domain.SocialSource
+	Social bool
+
+	// Enrich adds the details of their product page to the entries, if the
+	// source is a domain.ProductSource. Only the products missing from
+	// Products are fetched, and Products is updated with them.
+	Enrich   bool
+	Products *cache.Cache
+
+	// Covers downloads the posters of the entries into its backend, if the
+	// source is a domain.AssetSource. A nil Covers downloads nothing.
+	Covers *Covers
+
+	// Logger prints the progress and the warnings. It defaults to
+	// logging.Default().
+	Logger logging.Logger
+}
+
+func (opts Options) categories() []string {
+	if len(opts.Categories) == 0 {
+		return Categories
+	}
+	return opts.Categories
+}
+
+func (opts Options) filters() []string {
+	if len(opts.Filters) == 0 {
+		return DefaultFilters
+	}
+	return opts.Filters
+}
+
+func (opts Options) logger() logging.Logger {
+	if opts.Logger == nil {
+		return logging.Default()
+	}
+	return opts.Logger
+}
+
+// Failure tells why a collection could not be backed up
+type Failure struct {
+	Collection string   `json:"collection"`
+	Error      string   `json:"error"`
+	URLs       []string `json:"urls,omitempty"`
+}
+
+var _ domain.Serializable = (*Summary)(nil)
+
+// Summary tells which collections a backup saved and which it did not.
+// It is saved as the report of the backup.
+type Summary struct {
+	Saved    []string   `json:"saved"`
+	Missing  []string   `json:"missing"`
+	Failures []*Failure `json:"failures,omitempty"`
+}
+
+func newSummary(categories []string, filters []string) *Summary {
+	summary := &Summary{Saved: []string{}}
+	for _, category := range categories {
+		for _, filter := range filters {
+			if !HasCollection(category, filter) {
+				continue
+			}
+			summary.Missing = append(summary.Missing, domain.NewCollection(nil, category, filter, "").Slug())
+		}
+	}
+	return summary
+}
+
+func (s *Summary) saved(slug string) {
+	s.Saved = append(s.Saved, slug)
+	for i, missing := range s.Missing {
+		if missing == slug {
+			s.Missing = append(s.Missing[:i], s.Missing[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Summary) failed(slug string, err error) {
+	s.Failures = append(s.Failures, &Failure{
+		Collection: slug,
+		Error:      err.Error(),
+		URLs:       affectedURLs(err),
+	})
+}
+
+func (s *Summary) Slug() string {
+	return "report"
+}
+
+func (s *Summary) CSV() []*domain.Entry {
+	return nil
+}
+
+func (s *Summary) JSON() interface{} {
+	return s
+}
+
+// List backs up a list. Only opts.Covers and opts.Logger apply to lists.
+func List(ctx context.Context, src domain.Source, url string, back domain.Backend, opts Options) error {
+	err := back.Create()
+	if err != nil {
+		return err
+	}
+
+	_, err = saveList(ctx, src, url, back, opts.assetSource(src), opts)
+	return err
+}
+
+// saveList fetches a list, and its covers if assetSource is not nil
+func saveList(ctx context.Context, src domain.Source, url string, back domain.Backend, assetSource domain.AssetSource, opts Options) (*domain.List, error) {
+	list, err := src.List(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveCovers(ctx, assetSource, list.Entries, opts.Covers, back.Location()); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		opts.logger().Info("warning: %s: failed to download some covers: %s", list.Slug(), err)
+	}
+
+	return list, back.Save(list)
+}
+
+// assetSource returns src as a domain.AssetSource if opts.Covers is set and
+// src supports it, nil otherwise
+func (opts Options) assetSource(src domain.Source) domain.AssetSource {
+	if opts.Covers == nil {
+		return nil
+	}
+
+	assetSource, ok := src.(domain.AssetSource)
+	if !ok {
+		opts.logger().Info("warning: the covers can't be downloaded from this source")
+		return nil
+	}
+	return assetSource
+}
+
+// Lists backs up all the lists of a user, and an index of them. The
+// Summary lists the lists by URL.
+func Lists(ctx context.Context, src domain.Source, username string, back domain.Backend, opts Options) (*Summary, error) {
+	summary := &Summary{Saved: []string{}}
+
+	listsSource, ok := src.(domain.ListsSource)
+	if !ok {
+		return summary, errors.New("the lists of a user can't be enumerated from this source")
+	}
+
+	err := src.ValidateUser(ctx, username)
+	if err != nil {
+		return summary, err
+	}
+
+	urls, err := listsSource.Lists(ctx, username)
+	if err != nil {
+		return summary, err
+	}
+	summary.Missing = append(summary.Missing, urls...)
+
+	logger := opts.logger()
+	logger.Info("Backing up %d lists for user %s", len(urls), username)
+	if err := back.Create(); err != nil {
+		return summary, err
+	}
+
+	assetSource := opts.assetSource(src)
+	index := domain.NewListIndex(make([]*domain.ListInfo, 0, len(urls)), username)
+	slugs := map[string]string{}
+
+	for _, url := range urls {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		list, err := saveList(ctx, src, url, back, assetSource, opts)
+		if err != nil {
+			if !opts.KeepGoing || ctx.Err() != nil {
+				return summary, err
+			}
+			logger.Info("warning: failed to backup %s: %s", url, err)
+			summary.failed(url, err)
+			continue
+		}
+
+		if previous, ok := slugs[list.Slug()]; ok {
+			logger.Info("warning: %s and %s have the same title, only the last one is saved", previous, url)
+		}
+		slugs[list.Slug()] = url
+
+		index.Lists = append(index.Lists, &domain.ListInfo{
+			Title:   list.Title,
+			Slug:    list.Slug(),
+			Entries: len(list.Entries),
+			URL:     url,
+		})
+		summary.saved(url)
+	}
+
+	if err := back.Save(index); err != nil {
+		return summary, err
+	}
+
+	if len(summary.Failures) > 0 {
+		return summary, ErrIncomplete
+	}
+	return summary, nil
+}
+
+// fetchCollection fetches a collection, incrementally when possible
+func fetchCollection(ctx context.Context, src domain.Source, username string, category string, filter string, back domain.Backend, opts Options) (*domain.Collection, error) {
+	if incremental, ok := src.(domain.IncrementalSource); ok && opts.Incremental {
+		previous := domain.NewCollection(nil, category, filter, username)
+		err := back.Load(previous)
+
+		switch {
+		case err != nil:
+			opts.logger().Debug("%s: no previous backup to update (%s), fetching all the pages", previous.Slug(), err)
+		case previous.FullBackupAt == nil || time.Since(*previous.FullBackupAt) >= opts.FullEvery:
+			opts.logger().Debug("%s: full backup required", previous.Slug())
+		default:
+			collection, err := incremental.CollectionSince(ctx, username, category, filter, previous)
+			if !errors.Is(err, domain.ErrFullBackupNeeded) {
+				return collection, err
+			}
+			opts.logger().Debug("%s, fetching all the pages", err)
+		}
+	}
+
+	collection, err := src.Collection(ctx, username, category, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	collection.FullBackupAt = &now
+	return collection, nil
+}
+
+// Collection backs up a user collection. The returned Summary is never nil,
+// even when an error occurs or ctx is canceled. With opts.KeepGoing, the
+// failures are listed in the Summary and ErrIncomplete is returned.
+func Collection(ctx context.Context, src domain.Source, username string, back domain.Backend, opts Options) (*Summary, error) {
+	categories, filters := opts.categories(), opts.filters()
+	summary := newSummary(categories, filters)
+	logger := opts.logger()
+	extras := extraBackups(src, username, opts)
+
+	// the done dates are only needed by the done collections, and the
+	// episodes by the series done or in progress
+	needsJournal := contains(filters, domain.StateDone) || contains(filters, domain.StateInProgress)
+	if needsJournal {
+		summary.Missing = append(summary.Missing, domain.NewDiary(nil, username).Slug())
+	}
+	for _, extra := range extras {
+		summary.Missing = append(summary.Missing, extra.slug)
+	}
+
+	err := src.ValidateUser(ctx, username)
+	if err != nil {
+		return summary, err
+	}
+
+	logger.Info("Backing up collection for user %s", username)
+	back.Create()
+
+	// failed returns err unless the backup should keep going
+	failed := func(slug string, err error) error {
+		if !opts.KeepGoing || ctx.Err() != nil {
+			return err
+		}
+		logger.Info("warning: failed to backup %s: %s", slug, err)
+		summary.failed(slug, err)
+		return nil
+	}
+
+	productSource, ok := src.(domain.ProductSource)
+	if opts.Enrich && !ok {
+		logger.Info("warning: the entries can't be enriched from this source")
+	}
+
+	assetSource := opts.assetSource(src)
+
+	var dates []*domain.Entry
+	hasJournal := false
+	if needsJournal {
+		dates, err = src.Journal(ctx, username, opts.Categories)
+		hasJournal = err == nil
+		if err != nil {
+			if err := failed("journal", err); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	journal := indexJournal(dates)
+	// productCategories are the categories of the products of the journal
+	productCategories := map[string]string{}
+
+	for _, category := range categories {
+		for _, filter := range filters {
+			if !HasCollection(category, filter) {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return summary, err
+			}
+
+			collection, err := fetchCollection(ctx, src, username, category, filter, back, opts)
+			if err != nil {
+				if err := failed(category+"-"+filter, err); err != nil {
+					return summary, err
+				}
+				continue
+			}
+
+			if opts.Enrich && productSource != nil {
+				if err := enrich(ctx, productSource, collection.Entries, opts.Products, logger); err != nil {
+					if ctx.Err() != nil {
+						return summary, ctx.Err()
+					}
+					logger.Info("warning: %s: failed to fetch the details of some products: %s", collection.Slug(), err)
+				}
+			}
+
+			if err := saveCovers(ctx, assetSource, collection.Entries, opts.Covers, back.Location()); err != nil {
+				if ctx.Err() != nil {
+					return summary, ctx.Err()
+				}
+				logger.Info("warning: %s: failed to download some covers: %s", collection.Slug(), err)
+			}
+
+			for _, entry := range collection.Entries {
+				// the previous dates and episodes of an incremental backup
+				// are replaced by the ones of the journal, even when the
+				// journal no longer has any
+				if hasJournal {
+					entry.DoneDate = nil
+					entry.DoneDates = nil
+					entry.Episodes = nil
+				}
+				for _, d := range journal[entry.ID] {
+					productCategories[entry.ID] = category
+					if filter == domain.StateDone && d.DoneDate != nil {
+						entry.DoneDates = append(entry.DoneDates, *d.DoneDate)
+					}
+					entry.Episodes = append(entry.Episodes, d.Episodes...)
+				}
+				if len(entry.DoneDates) > 0 {
+					sort.SliceStable(entry.DoneDates, func(i, j int) bool {
+						return entry.DoneDates[i].Before(entry.DoneDates[j])
+					})
+					last := entry.DoneDates[len(entry.DoneDates)-1]
+					entry.DoneDate = &last
+				}
+			}
+
+			err = back.Save(collection)
+			if err != nil {
+				if err := failed(collection.Slug(), err); err != nil {
+					return summary, err
+				}
+				continue
+			}
+			summary.saved(collection.Slug())
+		}
+	}
+
+	if hasJournal {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		diary := newDiary(dates, productCategories, username)
+		if err := back.Save(diary); err != nil {
+			if err := failed(diary.Slug(), err); err != nil {
+				return summary, err
+			}
+		} else {
+			summary.saved(diary.Slug())
+		}
+	}
+
+	for _, extra := range extras {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		data, err := extra.fetch(ctx)
+		if err == nil {
+			err = back.Save(data)
+		}
+		if err != nil {
+			if err := failed(extra.slug, err); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		summary.saved(extra.slug)
+	}
+
+	if len(summary.Failures) > 0 {
+		return summary, ErrIncomplete
+	}
+	return summary, nil
+}
+
+// indexJournal indexes the entries of the journal by product ID, keeping
+// every event of a product
+func indexJournal(dates []*domain.Entry) map[string][]*domain.Entry {
+	journal := make(map[string][]*domain.Entry, len(dates))
+	for _, d := range dates {
+		journal[d.ID] = append(journal[d.ID], d)
+	}
+	return journal
+}
+
+// newDiary returns the timeline of the products done in the journal, from
+// the most recent to the oldest like the journal. The episodes are left out:
+// they are backed up with their series.
+func newDiary(dates []*domain.Entry, categories map[string]string, username string) *domain.Diary {
+	events := make([]*domain.DiaryEvent, 0, len(dates))
+	for _, d := range dates {
+		if d.DoneDate == nil {
+			continue
+		}
+		events = append(events, &domain.DiaryEvent{
+			Date:      *d.DoneDate,
+			ProductID: d.ID,
+			Category:  categories[d.ID],
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[j].Date.Before(events[i].Date)
+	})
+	return domain.NewDiary(events, username)
+}
+
+// enrich sets the details of the entries, fetching only the products
+// missing from the cache. The entries whose product failed are left as is.
+func enrich(ctx context.Context, src domain.ProductSource, entries []*domain.Entry, products *cache.Cache, logger logging.Logger) error {
+	details := map[string]*domain.Product{}
+	missing := make([]string, 0)
+	for _, entry := range entries {
+		if _, ok := details[entry.ID]; ok {
+			continue
+		}
+		if product, ok := products.Get(entry.ID); ok {
+			details[entry.ID] = product
+			continue
+		}
+		details[entry.ID] = nil
+		missing = append(missing, entry.ID)
+	}
+
+	var err error
+	if len(missing) > 0 {
+		logger.Debug("fetching the details of %d products", len(missing))
+
+		var fetched []*domain.Product
+		fetched, err = src.Products(ctx, missing)
+		for _, product := range fetched {
+			details[product.ID] = product
+			products.Set(product)
+		}
+
+		if err := products.Save(); err != nil {
+			logger.Info("warning: failed to save the product cache: %s", err)
+		}
+	}
+
+	for _, entry := range entries {
+		if product := details[entry.ID]; product != nil {
+			entry.Details = product
+		}
+	}
+	return err
+}
+
+// extraBackup is the backup of user data that is not a collection
+type extraBackup struct {
+	slug  string
+	fetch func(ctx context.Context) (domain.Serializable, error)
+}
+
+// extraBackups returns the extra backups enabled by opts that src supports
+func extraBackups(src domain.Source, username string, opts Options) []*extraBackup {
+	var extras []*extraBackup
+
+	if opts.Reviews {
+		if reviewSource, ok := src.(domain.ReviewSource); ok {
+			extras = append(extras, &extraBackup{"reviews", func(ctx context.Context) (domain.Serializable, error) {
+				return reviewSource.Reviews(ctx, username)
+			}})
+		} else {
+			opts.logger().Info("warning: the reviews can't be backed up from this source")
+		}
+	}
+
+	if opts.Social {
+		if socialSource, ok := src.(domain.SocialSource); ok {
+			extras = append(extras, &extraBackup{"social", func(ctx context.Context) (domain.Serializable, error) {
+				return socialSource.SocialGraph(ctx, username)
+			}})
+		} else {
+			opts.logger().Info("warning: the scouts and followers can't be backed up from this source")
+		}
+	}
+
+	return extras
 }
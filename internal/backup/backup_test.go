@@ -1,27 +1,105 @@
 package backup
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"go.mlcdf.fr/sc-backup/internal/backend/mock"
+	"go.mlcdf.fr/sc-backup/internal/cassette"
+	"go.mlcdf.fr/sc-backup/internal/checkpoint"
 	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+	"go.mlcdf.fr/sc-backup/internal/retry"
 )
 
+var record = flag.Bool("record", false, "record the cassettes against senscritique.com")
+
+// newCassetteClient returns a Client that replays the HTTP responses stored in
+// testdata/cassettes for the current test. Run the tests with -record to
+// record them again.
+func newCassetteClient(t *testing.T) *Client {
+	mode := cassette.Replay
+	if *record {
+		mode = cassette.Record
+	}
+
+	c, err := cassette.New(filepath.Join("testdata", "cassettes", t.Name()+".json"), mode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := c.Save(); err != nil {
+			t.Errorf("failed to save cassette: %s", err)
+		}
+	})
+
+	if mode == cassette.Replay {
+		// a missing response won't be recorded on the next attempt
+		return New(WithTransport(c), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+	}
+	return New(WithTransport(c))
+}
+
 func TestValidateUser(t *testing.T) {
+	client := newCassetteClient(t)
+
 	username := "username-that-does-not-exists"
-	err := validateUser(username)
+	err := client.validateUser(context.Background(), username)
 	if err == nil {
 		t.Errorf("username %s should not exist", username)
 	}
 
 	username = "mlcdf"
-	err = validateUser(username)
+	err = client.validateUser(context.Background(), username)
 	if err != nil {
 		t.Errorf("username %s should exist", username)
 	}
 }
 
+func TestClientOptions(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		if r.URL.Path != "/mlcdf" {
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithUserAgent("sc-backup/test"), WithConcurrency(0))
+
+	if err := client.ValidateUser(context.Background(), "mlcdf"); err != nil {
+		t.Errorf("username mlcdf should exist: %s", err)
+	}
+
+	if err := client.ValidateUser(context.Background(), "username-that-does-not-exists"); err == nil {
+		t.Errorf("username username-that-does-not-exists should not exist")
+	}
+
+	if userAgent != "sc-backup/test" {
+		t.Errorf("expected User-Agent sc-backup/test, got %s", userAgent)
+	}
+
+	if client.concurrency != 1 {
+		t.Errorf("expected the concurrency to be at least 1, got %d", client.concurrency)
+	}
+
+	if url := client.makeCollectionURL("mlcdf", "films", "done"); !strings.HasPrefix(url, server.URL+"/mlcdf/") {
+		t.Errorf("collection URL %s should start with the base URL", url)
+	}
+}
+
 func TestMakeListURL(t *testing.T) {
 	testCases := []struct {
 		url      string
@@ -64,8 +142,10 @@ func TestMakeListURL(t *testing.T) {
 }
 
 func TestBackupList(t *testing.T) {
+	client := newCassetteClient(t)
+
 	back := mock.NewBackend()
-	List("https://www.senscritique.com/liste/Vu_au_cinema/363578", back)
+	List(context.Background(), client, "https://www.senscritique.com/liste/Vu_au_cinema/363578", back, Options{})
 
 	stuff := back.Data["vu-au-cinema"]
 	if stuff == nil {
@@ -131,9 +211,25 @@ func TestBackupList(t *testing.T) {
 	}
 }
 
+// wholeJournalClient fetches the journal of all the categories, the only one
+// recorded in the cassette
+type wholeJournalClient struct {
+	*Client
+}
+
+func (c *wholeJournalClient) Journal(ctx context.Context, username string, categories []string) ([]*domain.Entry, error) {
+	return c.Client.Journal(ctx, username, nil)
+}
+
 func TestBackupCollection(t *testing.T) {
+	client := &wholeJournalClient{newCassetteClient(t)}
+
 	back := mock.NewBackend()
-	Collection("mlcdf", back)
+	// the cassette was recorded before the video games were supported
+	opts := Options{Categories: []string{"films", "series", "bd", "livres", "albums", "morceaux"}}
+	if _, err := Collection(context.Background(), client, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
 
 	stuff := back.Data["films-done"]
 	if stuff == nil {
@@ -165,3 +261,762 @@ func TestBackupCollection(t *testing.T) {
 		t.Errorf("entry.Authors cannot be empty %v", entry)
 	}
 }
+
+// partialDate parses a date of the journal
+func partialDate(s string) *domain.PartialDate {
+	date, err := domain.ParsePartialDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return &date
+}
+
+// dateString returns the ISO form of a date, or "" if it is nil
+func dateString(date *domain.PartialDate) string {
+	if date == nil {
+		return ""
+	}
+	return date.String()
+}
+
+// collectionCount is the number of collections of a default backup
+func collectionCount() int {
+	return len(newSummary(Categories, DefaultFilters).Missing)
+}
+
+// fakeSource is an in-memory domain.Source
+type fakeSource struct {
+	collections map[string][]*domain.Entry
+	journal     []*domain.Entry
+
+	// journalCategories are the categories the journal was last fetched for
+	journalCategories []string
+	journalFetches    int
+}
+
+func (f *fakeSource) ValidateUser(ctx context.Context, username string) error {
+	if username != "mlcdf" {
+		return fmt.Errorf("username %s does not exist or has a limited profil", username)
+	}
+	return nil
+}
+
+func (f *fakeSource) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	return domain.NewCollection(f.collections[category+"-"+filter], category, filter, username), nil
+}
+
+func (f *fakeSource) List(ctx context.Context, url string) (*domain.List, error) {
+	return domain.NewList(f.collections["films-done"], "Vu au cinéma", ""), nil
+}
+
+func (f *fakeSource) Journal(ctx context.Context, username string, categories []string) ([]*domain.Entry, error) {
+	f.journalCategories = categories
+	f.journalFetches++
+	return f.journal, nil
+}
+
+func TestCollectionWithSource(t *testing.T) {
+	src := &fakeSource{
+		collections: map[string][]*domain.Entry{
+			"films-done": {{ID: "1", Title: "Munich"}, {ID: "2", Title: "Tenet"}},
+			"films-wish": {{ID: "3", Title: "Ava"}},
+		},
+		journal: []*domain.Entry{{ID: "2", DoneDate: partialDate("2020-09-13")}, {ID: "3", DoneDate: partialDate("2020-01-01")}},
+	}
+
+	back := mock.NewBackend()
+	if _, err := Collection(context.Background(), src, "username-that-does-not-exists", back, Options{}); err == nil {
+		t.Errorf("expected an error for an unknown user")
+	}
+
+	summary, err := Collection(context.Background(), src, "mlcdf", back, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Saved) != len(back.Data) || len(summary.Missing) != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// the collections and the diary
+	if l := len(back.Data); l != collectionCount()+1 {
+		t.Errorf("expected %d collections and the diary, got %d files", collectionCount(), l)
+	}
+
+	done := back.Data["films-done"].(*domain.Collection)
+	if done.Entries[0].DoneDate != nil {
+		t.Errorf("expected no done date for %s, got %s", done.Entries[0].Title, done.Entries[0].DoneDate)
+	}
+	if dateString(done.Entries[1].DoneDate) != "2020-09-13" {
+		t.Errorf("expected done date 2020-09-13 for %s, got %s", done.Entries[1].Title, done.Entries[1].DoneDate)
+	}
+
+	if wish := back.Data["films-wish"].(*domain.Collection); wish.Entries[0].DoneDate != nil {
+		t.Errorf("done dates should only be set on done collections")
+	}
+
+	if err := List(context.Background(), src, "https://www.senscritique.com/liste/Vu_au_cinema/363578", back, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if back.Data["vu-au-cinema"] == nil {
+		t.Errorf("slug vu-au-cinema not found")
+	}
+}
+
+// cancelingSource cancels the backup once it has fetched n collections
+type cancelingSource struct {
+	fakeSource
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingSource) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	if c.n--; c.n == 0 {
+		c.cancel()
+	}
+	return c.fakeSource.Collection(ctx, username, category, filter)
+}
+
+func TestCollectionCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := &cancelingSource{n: 3, cancel: cancel}
+
+	back := mock.NewBackend()
+	summary, err := Collection(ctx, src, "mlcdf", back, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the backup to be canceled, got %v", err)
+	}
+
+	if len(summary.Saved) != 3 || len(back.Data) != 3 {
+		t.Errorf("expected 3 saved collections, got %v", summary.Saved)
+	}
+
+	// the diary is saved after the collections
+	if l := len(summary.Missing); l != collectionCount()-3+1 {
+		t.Errorf("expected %d missing collections and the diary, got %d", collectionCount()-3, l)
+	}
+}
+
+// failingSource fails to fetch some collections
+type failingSource struct {
+	fakeSource
+	failures map[string]error
+}
+
+func (f *failingSource) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	if err := f.failures[category+"-"+filter]; err != nil {
+		return nil, err
+	}
+	return f.fakeSource.Collection(ctx, username, category, filter)
+}
+
+func TestCollectionKeepGoing(t *testing.T) {
+	url := "https://old.senscritique.com/mlcdf/collection/wish/morceaux/all/all/all/all/all/all/all/page-3"
+	src := &failingSource{failures: map[string]error{
+		"morceaux-wish": pool.Errors{&PageError{url, fmt.Errorf("error: http 503 for url %s", url)}},
+	}}
+
+	if _, err := Collection(context.Background(), src, "mlcdf", mock.NewBackend(), Options{}); err == nil {
+		t.Errorf("expected the backup to stop at the first failure")
+	}
+
+	back := mock.NewBackend()
+	summary, err := Collection(context.Background(), src, "mlcdf", back, Options{KeepGoing: true})
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("expected ErrIncomplete, got %v", err)
+	}
+
+	if l := len(back.Data); l != collectionCount()-1+1 {
+		t.Errorf("expected %d saved collections and the diary, got %d files", collectionCount()-1, l)
+	}
+
+	if len(summary.Missing) != 1 || summary.Missing[0] != "morceaux-wish" {
+		t.Errorf("expected morceaux-wish to be missing, got %v", summary.Missing)
+	}
+
+	if len(summary.Failures) != 1 {
+		t.Fatalf("expected 1 failure, got %d", len(summary.Failures))
+	}
+
+	failure := summary.Failures[0]
+	if failure.Collection != "morceaux-wish" || len(failure.URLs) != 1 || failure.URLs[0] != url {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+}
+
+const malformedPage = `<html><body><ul>
+<li class="elco-collection-item">
+	<div class="elco-collection-content"><figure class="elco-collection-poster" data-sc-product-id="388729"></figure></div>
+	<div class="elco-product-detail"><h2 class="elco-title"><a>Munich</a> <span class="elco-date">(2005)</span></h2></div>
+	<div class="elco-collection-rating user"><a><div><span>8</span></div></a></div>
+</li>
+<li class="elco-collection-item">
+	<div class="elco-collection-content"><figure class="elco-collection-poster" data-sc-product-id="38918801"></figure></div>
+	<div class="elco-product-detail"><h2 class="elco-title"><a>Tenet</a> <span class="elco-date">(20xx)</span></h2></div>
+	<div class="elco-collection-rating user"><a><div><span>huit</span></div></a></div>
+</li>
+</ul></body></html>`
+
+func TestParseErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(malformedPage))
+	}))
+	defer server.Close()
+	url := server.URL + "/mlcdf/collection/done/films/all/all/all/all/all/all/all/page-2"
+
+	strict := New(WithBaseURL(server.URL), WithStrictParsing(true))
+	_, err := strict.extractPage(context.Background(), url, parseDocument)
+
+	var parseErrors ParseErrors
+	if !errors.As(err, &parseErrors) {
+		t.Fatalf("expected ParseErrors in strict mode, got %v", err)
+	}
+
+	if len(parseErrors) != 2 {
+		t.Fatalf("expected 2 parse errors, got %d", len(parseErrors))
+	}
+
+	expected := ParseError{URL: url, Index: 1, Field: "year", Raw: "(20xx)"}
+	if e := parseErrors[0]; e.URL != expected.URL || e.Index != expected.Index || e.Field != expected.Field || e.Raw != expected.Raw {
+		t.Errorf("expected %+v, got %+v", expected, e)
+	}
+
+	if e := parseErrors[1]; e.Field != "rating" || e.Raw != "huit" {
+		t.Errorf("expected a rating parse error, got %+v", e)
+	}
+
+	lenient := New(WithBaseURL(server.URL))
+	entries, err := lenient.extractPage(context.Background(), url, parseDocument)
+	if err != nil {
+		t.Fatalf("expected no error in lenient mode, got %s", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	if e := entries[1]; e.Title != "Tenet" || e.Year != 0 || e.Rating != 0 {
+		t.Errorf("expected Tenet without year nor rating, got %+v", e)
+	}
+
+	if l := len(lenient.ParseErrors()); l != 2 {
+		t.Errorf("expected 2 reported parse errors, got %d", l)
+	}
+}
+
+func TestJournalParseErrors(t *testing.T) {
+	page := `<html><body><span class="elco-collection-count">2</span><ul>` +
+		`<li class="eldi-list-item" data-sc-datedone="2020-13-04">` +
+		`<div class="eldi-collection-container"><figure class="eldi-collection-poster" data-sc-product-id="491576"></figure></div></li>` +
+		`<li class="eldi-list-item" data-sc-datedone="2020-10-25">` +
+		`<div class="eldi-collection-container"><figure class="eldi-collection-poster" data-sc-product-id="388729"></figure></div></li>` +
+		`</ul></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	strict := New(WithBaseURL(server.URL), WithStrictParsing(true))
+	var parseErrors ParseErrors
+	if _, err := strict.Journal(context.Background(), "mlcdf", nil); !errors.As(err, &parseErrors) {
+		t.Errorf("expected ParseErrors in strict mode, got %v", err)
+	}
+
+	lenient := New(WithBaseURL(server.URL))
+	entries, err := lenient.Journal(context.Background(), "mlcdf", nil)
+	if err != nil {
+		t.Fatalf("expected no error in lenient mode, got %s", err)
+	}
+
+	if l := len(entries); l != 2 {
+		t.Fatalf("expected 2 entries, got %d", l)
+	}
+	if e := entries[0]; e.ID != "491576" || e.DoneDate != nil {
+		t.Errorf("expected 491576 without done date, got %+v", e)
+	}
+	if e := entries[1]; e.ID != "388729" || dateString(e.DoneDate) != "2020-10-25" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	if errs := lenient.ParseErrors(); len(errs) != 1 || errs[0].Field != "done date" {
+		t.Errorf("expected a reported done date parse error, got %v", errs)
+	}
+}
+
+func collectionPage(ids ...string) string {
+	page := `<html><body><ul><li data-sc-collection-filter="done"><span>Vus <span>(20)</span></span></li></ul><ul>`
+	for _, id := range ids {
+		page += `<li class="elco-collection-item"><div class="elco-collection-content"><figure class="elco-collection-poster" data-sc-product-id="` + id + `"></figure></div></li>`
+	}
+	return page + `</ul></body></html>`
+}
+
+func TestCollectionResume(t *testing.T) {
+	firstPage := make([]string, 18)
+	for i := range firstPage {
+		firstPage[i] = fmt.Sprint(i + 1)
+	}
+
+	requests := map[string]int{}
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch {
+		case strings.HasSuffix(r.URL.Path, "page-"):
+			w.Write([]byte(collectionPage(firstPage...)))
+		case strings.HasSuffix(r.URL.Path, "page-2") && !failing:
+			w.Write([]byte(collectionPage("19", "20")))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cpDir := filepath.Join(t.TempDir(), checkpoint.Dirname)
+	client := New(WithBaseURL(server.URL), WithCheckpoint(checkpoint.New(cpDir)), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	if _, err := client.Collection(context.Background(), "mlcdf", "films", "done"); err == nil {
+		t.Fatalf("expected page 2 to fail")
+	}
+
+	// resume from the checkpoint saved on disk
+	failing = false
+	client = New(WithBaseURL(server.URL), WithCheckpoint(checkpoint.New(cpDir)), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	collection, err := client.Collection(context.Background(), "mlcdf", "films", "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(collection.Entries); l != 20 {
+		t.Fatalf("expected 20 entries, got %d", l)
+	}
+
+	for i, entry := range collection.Entries {
+		if expected := fmt.Sprint(i + 1); entry.ID != expected {
+			t.Errorf("expected entry %s at index %d, got %s", expected, i, entry.ID)
+		}
+	}
+
+	firstPageURL := "/mlcdf/collection/done/films/all/all/all/all/all/all/all/page-"
+	if requests[firstPageURL] != 1 {
+		t.Errorf("the first page should have been fetched once, got %d", requests[firstPageURL])
+	}
+	if requests[firstPageURL+"2"] != 2 {
+		t.Errorf("the second page should have been fetched twice, got %d", requests[firstPageURL+"2"])
+	}
+}
+
+func TestCollectionSince(t *testing.T) {
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if strings.HasSuffix(r.URL.Path, "page-2") {
+			w.Write([]byte(collectionPage(ids[18:]...)))
+			return
+		}
+		w.Write([]byte(collectionPage(ids[:18]...)))
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL))
+	secondPageURL := "/mlcdf/collection/done/films/all/all/all/all/all/all/all/page-2"
+
+	previous := func(ids ...string) *domain.Collection {
+		entries := make([]*domain.Entry, len(ids))
+		for i, id := range ids {
+			entries[i] = &domain.Entry{ID: id}
+		}
+		return domain.NewCollection(entries, "films", "done", "mlcdf")
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		requests = map[string]int{}
+		collection, err := client.CollectionSince(context.Background(), "mlcdf", "films", "done", previous(ids...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := len(collection.Entries); l != 20 {
+			t.Fatalf("expected 20 entries, got %d", l)
+		}
+		if requests[secondPageURL] != 0 {
+			t.Errorf("the second page should not have been fetched")
+		}
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		requests = map[string]int{}
+		prev := previous(ids...)
+		prev.Entries[3].Rating = 7
+
+		collection, err := client.CollectionSince(context.Background(), "mlcdf", "films", "done", prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rating := collection.Entries[3].Rating; rating != 0 {
+			t.Errorf("expected the rating to be updated, got %d", rating)
+		}
+		if requests[secondPageURL] != 1 {
+			t.Errorf("the second page should have been fetched once, got %d", requests[secondPageURL])
+		}
+	})
+
+	t.Run("out of sync", func(t *testing.T) {
+		_, err := client.CollectionSince(context.Background(), "mlcdf", "films", "done", previous(ids[:18]...))
+		if !errors.Is(err, domain.ErrFullBackupNeeded) {
+			t.Errorf("expected a full backup to be needed, got %v", err)
+		}
+	})
+}
+
+// incrementalSource is a fakeSource that counts the incremental updates
+type incrementalSource struct {
+	fakeSource
+	updates int
+}
+
+func (i *incrementalSource) CollectionSince(ctx context.Context, username string, category string, filter string, previous *domain.Collection) (*domain.Collection, error) {
+	i.updates++
+	collection := domain.NewCollection(previous.Entries, category, filter, username)
+	collection.FullBackupAt = previous.FullBackupAt
+	return collection, nil
+}
+
+func TestCollectionIncremental(t *testing.T) {
+	src := &incrementalSource{fakeSource: fakeSource{
+		collections: map[string][]*domain.Entry{"films-done": {{ID: "1", Title: "Munich"}}},
+	}}
+	back := mock.NewBackend()
+	opts := Options{Incremental: true, FullEvery: time.Hour}
+
+	// nothing to update yet
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+	if src.updates != 0 {
+		t.Errorf("expected a full backup, got %d incremental updates", src.updates)
+	}
+
+	fullBackupAt := back.Data["films-done"].(*domain.Collection).FullBackupAt
+	if fullBackupAt == nil {
+		t.Fatalf("expected the full backup time to be set")
+	}
+
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+	if expected := collectionCount(); src.updates != expected {
+		t.Errorf("expected %d incremental updates, got %d", expected, src.updates)
+	}
+	if at := back.Data["films-done"].(*domain.Collection).FullBackupAt; !at.Equal(*fullBackupAt) {
+		t.Errorf("expected the full backup time to be kept, got %s", at)
+	}
+
+	// the last full backup is too old
+	src.updates = 0
+	opts.FullEvery = 0
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+	if src.updates != 0 {
+		t.Errorf("expected a full backup, got %d incremental updates", src.updates)
+	}
+}
+
+func TestCollectionIncrementalDates(t *testing.T) {
+	src := &incrementalSource{fakeSource: fakeSource{
+		collections: map[string][]*domain.Entry{"films-done": {{ID: "1", Title: "Munich"}, {ID: "2", Title: "Tenet"}}},
+		journal:     []*domain.Entry{{ID: "1", DoneDate: partialDate("2020-10-25")}, {ID: "2", DoneDate: partialDate("2020-09-13")}},
+	}}
+	back := mock.NewBackend()
+	opts := Options{Categories: []string{"films"}, Filters: []string{"done"}, Incremental: true, FullEvery: time.Hour}
+
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the date of Munich was changed and the one of Tenet removed since
+	src.journal = []*domain.Entry{{ID: "1", DoneDate: partialDate("2021-01-02")}}
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+	if src.updates != 1 {
+		t.Fatalf("expected an incremental update, got %d", src.updates)
+	}
+
+	entries := back.Data["films-done"].(*domain.Collection).Entries
+	if munich := entries[0]; dateString(munich.DoneDate) != "2021-01-02" || len(munich.DoneDates) != 1 {
+		t.Errorf("expected the new date of %s, got %s %v", munich.Title, munich.DoneDate, munich.DoneDates)
+	}
+	if tenet := entries[1]; tenet.DoneDate != nil || tenet.DoneDates != nil {
+		t.Errorf("expected no date for %s, got %s %v", tenet.Title, tenet.DoneDate, tenet.DoneDates)
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	categories, err := ParseCategories("")
+	if err != nil || len(categories) != len(Categories) {
+		t.Errorf("expected all the categories, got %v (%v)", categories, err)
+	}
+
+	categories, err = ParseCategories(" livres,Films,,films")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(categories, ",") != "films,livres" {
+		t.Errorf("expected films,livres, got %v", categories)
+	}
+
+	if _, err := ParseCategories("films,podcasts"); err == nil {
+		t.Errorf("expected an error for an unknown category")
+	}
+
+	if _, err := ParseFilters("done,seen"); err == nil {
+		t.Errorf("expected an error for an unknown filter")
+	}
+}
+
+func TestCollectionSubset(t *testing.T) {
+	src := &fakeSource{
+		collections: map[string][]*domain.Entry{"films-done": {{ID: "1", Title: "Munich"}}},
+		journal:     []*domain.Entry{{ID: "1", DoneDate: partialDate("2020-09-13")}},
+	}
+	back := mock.NewBackend()
+
+	summary, err := Collection(context.Background(), src, "mlcdf", back, Options{
+		Categories: []string{"films"},
+		Filters:    []string{"done"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(back.Data) != 2 || back.Data["films-done"] == nil || back.Data["diary"] == nil {
+		t.Errorf("expected films-done and the diary only, got %d files", len(back.Data))
+	}
+	if len(summary.Saved) != 2 || len(summary.Missing) != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if strings.Join(src.journalCategories, ",") != "films" {
+		t.Errorf("expected the journal to be fetched for films, got %v", src.journalCategories)
+	}
+	if date := back.Data["films-done"].(*domain.Collection).Entries[0].DoneDate; dateString(date) != "2020-09-13" {
+		t.Errorf("expected done date 2020-09-13, got %s", date)
+	}
+
+	// the journal is useless without the done collections
+	src.journalFetches = 0
+	if _, err := Collection(context.Background(), src, "mlcdf", back, Options{Filters: []string{"wish"}}); err != nil {
+		t.Fatal(err)
+	}
+	if src.journalFetches != 0 {
+		t.Errorf("the journal should not have been fetched")
+	}
+}
+
+func TestHasCollection(t *testing.T) {
+	if !HasCollection("jeuxvideo", domain.StateInProgress) {
+		t.Errorf("video games can be in progress")
+	}
+	if HasCollection("films", domain.StateInProgress) {
+		t.Errorf("films can't be in progress")
+	}
+	if HasCollection("podcasts", domain.StateDone) {
+		t.Errorf("podcasts is not a known category")
+	}
+}
+
+func TestCollectionInProgress(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL))
+	if _, err := client.Collection(context.Background(), "mlcdf", "series", domain.StateInProgress); err == nil {
+		t.Errorf("expected an error for the in progress collections")
+	}
+	if requests != 0 {
+		t.Errorf("no page should be fetched, got %d requests", requests)
+	}
+
+	if filters, _ := ParseFilters(""); !reflect.DeepEqual(filters, DefaultFilters) {
+		t.Errorf("expected the default filters, got %v", filters)
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	page := `<ul><li class="elco-collection-item"><div class="elco-collection-content"><figure class="elco-collection-poster" data-sc-product-id="9487960"></figure></div>` +
+		`<div class="elco-product-detail"><h2 class="elco-title"><a href="/jeuvideo/Hades/9487960">Hades</a></h2>` +
+		`<ul><li class="elco-gamesystem">PC</li><li class="elco-gamesystem"> Switch </li></ul></div></li></ul>`
+
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parseDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if platforms := entries[0].Platforms; len(platforms) != 2 || platforms[1] != "Switch" {
+		t.Errorf("unexpected platforms %v", platforms)
+	}
+}
+
+// reviewingSource is a fakeSource that also fetches reviews
+type reviewingSource struct {
+	fakeSource
+}
+
+func (r *reviewingSource) Reviews(ctx context.Context, username string) (*domain.Reviews, error) {
+	return domain.NewReviews([]*domain.Review{{ID: "1", ProductID: "2"}}, username), nil
+}
+
+func TestCollectionReviews(t *testing.T) {
+	back := mock.NewBackend()
+	opts := Options{Categories: []string{"films"}, Reviews: true}
+
+	summary, err := Collection(context.Background(), &reviewingSource{}, "mlcdf", back, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviews, ok := back.Data["reviews"].(*domain.Reviews); !ok || len(reviews.Reviews) != 1 {
+		t.Errorf("expected the reviews to be saved")
+	}
+	if len(summary.Missing) != 0 {
+		t.Errorf("unexpected missing collections %v", summary.Missing)
+	}
+
+	// the fakeSource can't fetch the reviews
+	back = mock.NewBackend()
+	if _, err := Collection(context.Background(), &fakeSource{}, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+	if back.Data["reviews"] != nil {
+		t.Errorf("expected no reviews")
+	}
+}
+
+func TestExtractEpisodes(t *testing.T) {
+	page := `<ul><li class="eldi-list-item"><h3>Décembre 2020</h3></li>` +
+		`<li class="eldi-list-item" data-sc-datedone="2020-12-04">` +
+		`<div class="eldi-collection-container"><figure class="eldi-collection-poster" data-sc-product-id="491576"></figure></div>` +
+		`<div class="eldi-collection-container"><div class="eldi-collection-episode" data-sc-product-id="8853524" data-sc-episode-id="1234">` +
+		`<span class="eldi-collection-episode-number">Saison 2 - Épisode 13</span></div></div>` +
+		`<div class="eldi-collection-container"><div class="eldi-collection-episode" data-sc-product-id="8853524" data-sc-episode-id="1235">` +
+		`<span class="eldi-collection-episode-number">Spécial</span></div></div></li></ul>`
+
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := extractDoneDate(document)
+	var parseErrors ParseErrors
+	if !errors.As(err, &parseErrors) || len(parseErrors) != 1 || parseErrors[0].Field != "episode number" {
+		t.Errorf("expected a parse error for the episode number, got %v", err)
+	}
+
+	if parseErrors[0].Index != 1 {
+		t.Errorf("expected the index of the journal item, got %d", parseErrors[0].Index)
+	}
+
+	if l := len(entries); l != 3 {
+		t.Fatalf("expected 3 entries, got %d", l)
+	}
+
+	if entry := entries[0]; entry.ID != "491576" || dateString(entry.DoneDate) != "2020-12-04" || entry.Episodes != nil {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	expected := domain.Episode{ID: "1234", SeriesID: "8853524", Season: 2, Number: 13, WatchedDate: partialDate("2020-12-04")}
+	if entry := entries[1]; entry.ID != "8853524" || entry.DoneDate != nil || len(entry.Episodes) != 1 || !reflect.DeepEqual(*entry.Episodes[0], expected) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	// the episode whose number can't be parsed is kept without it
+	expected = domain.Episode{ID: "1235", SeriesID: "8853524", WatchedDate: partialDate("2020-12-04")}
+	if entry := entries[2]; entry.ID != "8853524" || len(entry.Episodes) != 1 || !reflect.DeepEqual(*entry.Episodes[0], expected) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestCollectionEpisodes(t *testing.T) {
+	src := &fakeSource{
+		collections: map[string][]*domain.Entry{
+			"series-done":        {{ID: "1", Title: "The Fall", Episodes: []*domain.Episode{{ID: "old"}}}},
+			"series-in-progress": {{ID: "2", Title: "Dark"}},
+		},
+		journal: []*domain.Entry{
+			{ID: "1", DoneDate: partialDate("2020-09-13")},
+			{ID: "1", Episodes: []*domain.Episode{{ID: "10", SeriesID: "1", Season: 3, Number: 6}}},
+			{ID: "2", Episodes: []*domain.Episode{{ID: "20", SeriesID: "2", Season: 1, Number: 1}}},
+			{ID: "2", Episodes: []*domain.Episode{{ID: "21", SeriesID: "2", Season: 1, Number: 2}}},
+		},
+	}
+	back := mock.NewBackend()
+
+	opts := Options{Categories: []string{"series"}, Filters: []string{domain.StateDone, domain.StateInProgress}}
+	if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	done := back.Data["series-done"].(*domain.Collection).Entries[0]
+	if dateString(done.DoneDate) != "2020-09-13" || len(done.Episodes) != 1 || done.Episodes[0].ID != "10" {
+		t.Errorf("unexpected entry %+v", done)
+	}
+
+	inProgress := back.Data["series-in-progress"].(*domain.Collection).Entries[0]
+	if inProgress.DoneDate != nil || len(inProgress.Episodes) != 2 {
+		t.Errorf("unexpected entry %+v", inProgress)
+	}
+}
+
+func TestCollectionDiary(t *testing.T) {
+	src := &fakeSource{
+		collections: map[string][]*domain.Entry{
+			"films-done": {{ID: "1", Title: "Munich", DoneDates: []domain.PartialDate{*partialDate("2010-01-01")}}},
+			"bd-done":    {{ID: "2", Title: "Blacksad"}},
+		},
+		journal: []*domain.Entry{
+			{ID: "1", DoneDate: partialDate("2020-10-25")},
+			{ID: "2", DoneDate: partialDate("2020-09-13")},
+			{ID: "3", DoneDate: partialDate("2020-05-24")},
+			{ID: "1", DoneDate: partialDate("2016-07-09")},
+		},
+	}
+	back := mock.NewBackend()
+
+	if _, err := Collection(context.Background(), src, "mlcdf", back, Options{Filters: []string{"done"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	munich := back.Data["films-done"].(*domain.Collection).Entries[0]
+	if len(munich.DoneDates) != 2 || munich.DoneDates[0].String() != "2016-07-09" || dateString(munich.DoneDate) != "2020-10-25" {
+		t.Errorf("expected the rewatch of %s to be kept, got %v", munich.Title, munich.DoneDates)
+	}
+
+	diary := back.Data["diary"].(*domain.Diary)
+	expected := []domain.DiaryEvent{
+		{Date: *partialDate("2020-10-25"), ProductID: "1", Category: "films"},
+		{Date: *partialDate("2020-09-13"), ProductID: "2", Category: "bd"},
+		{Date: *partialDate("2020-05-24"), ProductID: "3"},
+		{Date: *partialDate("2016-07-09"), ProductID: "1", Category: "films"},
+	}
+	if len(diary.Events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(diary.Events))
+	}
+	for i, event := range diary.Events {
+		if *event != expected[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, expected[i], *event)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/checkpoint"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/logging"
+	"go.mlcdf.fr/sc-backup/internal/ratelimit"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// DefaultBaseURL is the server-side rendered website
+const DefaultBaseURL = "https://old.senscritique.com"
+
+// DefaultConcurrency is the default number of pages fetched at once
+const DefaultConcurrency = 20
+
+var _ domain.Source = (*Client)(nil)
+
+// Client scrapes the server-side rendered website
+type Client struct {
+	baseURL     string
+	http        *http.Client
+	userAgent   string
+	concurrency int
+	logger      logging.Logger
+	limiter     *ratelimit.Limiter
+	retry       retry.Policy
+	strict      bool
+	checkpoint  *checkpoint.Checkpoint
+
+	mu          sync.Mutex
+	parseErrors ParseErrors
+
+	// sleep waits between two attempts. It is replaced in tests.
+	sleep func(context.Context, time.Duration) error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL sets the URL of the website, e.g. a mirror or a test server
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithTransport sets the transport used to send the requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.http.Transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of each request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.http.Timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header of each request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithConcurrency sets the number of pages fetched at once
+func WithConcurrency(concurrency int) Option {
+	return func(c *Client) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithLimiter shares a rate limiter with the other clients of the run. The
+// wait for the limiter doesn't count against the timeout of the requests.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithRetryPolicy sets how the failed requests are retried
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithStrictParsing aborts on the first field that can't be parsed. Otherwise
+// the entry is kept with the field left empty and the error is reported by
+// ParseErrors.
+func WithStrictParsing(strict bool) Option {
+	return func(c *Client) {
+		c.strict = strict
+	}
+}
+
+// WithCheckpoint records the fetched pages in cp, and skips the pages it
+// already holds
+func WithCheckpoint(cp *checkpoint.Checkpoint) Option {
+	return func(c *Client) {
+		c.checkpoint = cp
+	}
+}
+
+// New returns a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		baseURL: DefaultBaseURL,
+		http: &http.Client{
+			Timeout: time.Second * 20,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		concurrency: DefaultConcurrency,
+		logger:      logging.Default(),
+		retry:       retry.DefaultPolicy,
+		sleep:       ratelimit.Sleep,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	return c
+}
+
+// request GETs the url. Transport errors and retryable statuses are retried
+// according to the client's retry policy, until ctx is done.
+func (c *Client) request(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to GET %s", url)
+	}
+
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	res, err := retry.Do(ctx, c.retry, c.logger, c.sleep, func() (*http.Response, error) {
+		if _, err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return c.do(req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode > 400 {
+		res.Body.Close()
+		return nil, fmt.Errorf("error: http %d for url %s", res.StatusCode, res.Request.URL)
+	}
+	return res, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	c.logger.Debug("GET %s", req.URL)
+
+	res, err := c.http.Do(req)
+
+	// check for response error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to GET %s", req.URL)
+	}
+
+	return res, nil
+}
+
+// checkParse returns err in strict mode. Otherwise it keeps the parse errors
+// as warnings and only returns the other errors.
+func (c *Client) checkParse(err error) error {
+	var parseErrors ParseErrors
+	if err == nil || c.strict || !errors.As(err, &parseErrors) {
+		return err
+	}
+
+	c.mu.Lock()
+	c.parseErrors = append(c.parseErrors, parseErrors...)
+	c.mu.Unlock()
+
+	for _, e := range parseErrors {
+		c.logger.Debug("warning: %s", e)
+	}
+	return nil
+}
+
+// ParseErrors returns the fields that could not be parsed so far
+func (c *Client) ParseErrors() ParseErrors {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append(ParseErrors(nil), c.parseErrors...)
+}
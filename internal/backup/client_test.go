@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mlcdf.fr/sc-backup/internal/ratelimit"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// flakyServer fails with the given statuses before answering 200
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newRetryClient returns a client that records the delays instead of sleeping
+func newRetryClient(server *httptest.Server, delays *[]time.Duration, maxAttempts int) *Client {
+	c := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Minute,
+	}))
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return c
+}
+
+func TestRequestRetriesTransientErrors(t *testing.T) {
+	server, calls := flakyServer(t, nil, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests)
+
+	var delays []time.Duration
+	res, err := newRetryClient(server, &delays, 4).request(context.Background(), server.URL+"/mlcdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if *calls != 4 {
+		t.Errorf("expected 4 calls, got %d", *calls)
+	}
+
+	if len(delays) != 3 {
+		t.Fatalf("expected 3 delays, got %v", delays)
+	}
+
+	for i, delay := range delays {
+		max := 100 * time.Millisecond << uint(i)
+		if delay < max/2 || delay > max {
+			t.Errorf("delay %d should be between %s and %s, got %s", i, max/2, max, delay)
+		}
+	}
+}
+
+func TestRequestHonorsRetryAfter(t *testing.T) {
+	server, _ := flakyServer(t, http.Header{"Retry-After": {"7"}}, http.StatusTooManyRequests)
+
+	var delays []time.Duration
+	res, err := newRetryClient(server, &delays, 4).request(context.Background(), server.URL+"/mlcdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if len(delays) != 1 || delays[0] != 7*time.Second {
+		t.Errorf("expected a single 7s delay, got %v", delays)
+	}
+}
+
+func TestRequestGivesUp(t *testing.T) {
+	server, calls := flakyServer(t, nil, 503, 503, 503, 503, 503)
+
+	var delays []time.Duration
+	if _, err := newRetryClient(server, &delays, 3).request(context.Background(), server.URL+"/mlcdf"); err == nil {
+		t.Errorf("expected an error after 3 attempts")
+	}
+
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestRequestDoesNotRetryFatalStatuses(t *testing.T) {
+	server, calls := flakyServer(t, nil, http.StatusNotFound)
+
+	var delays []time.Duration
+	if _, err := newRetryClient(server, &delays, 4).request(context.Background(), server.URL+"/mlcdf"); err == nil {
+		t.Errorf("expected an error for a 404")
+	}
+
+	if *calls != 1 || len(delays) != 0 {
+		t.Errorf("a 404 should not be retried: %d calls, delays %v", *calls, delays)
+	}
+}
+
+func TestRequestCanceled(t *testing.T) {
+	server, calls := flakyServer(t, nil, 503, 503, 503, 503, 503)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(WithBaseURL(server.URL))
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	if _, err := c.request(ctx, server.URL+"/mlcdf"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be canceled, got %v", err)
+	}
+
+	if *calls != 1 {
+		t.Errorf("expected a single call, got %d", *calls)
+	}
+}
+
+func TestRequestRateLimitedDoesNotTimeOut(t *testing.T) {
+	server, _ := flakyServer(t, nil)
+
+	// the workers wait up to 2s for a token, far longer than the timeout
+	limiter := ratelimit.New(1, 1, 0)
+	c := New(WithBaseURL(server.URL), WithTimeout(200*time.Millisecond), WithLimiter(limiter), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := c.request(context.Background(), server.URL+"/mlcdf")
+			if err == nil {
+				res.Body.Close()
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("worker %d: %s", i, err)
+		}
+	}
+	if requests, waited := limiter.Stats(); requests != 3 || waited < time.Second {
+		t.Errorf("expected 3 requests waiting for the limiter, got %d waiting %s", requests, waited)
+	}
+}
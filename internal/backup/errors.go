@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+// PageError is an error that occurred while fetching or parsing a page
+type PageError struct {
+	URL string
+	Err error
+}
+
+func (e *PageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// pageError wraps err into a PageError, unless it is nil or already tells
+// which pages are affected
+func pageError(url string, err error) error {
+	if err == nil || len(affectedURLs(err)) > 0 {
+		return err
+	}
+	return &PageError{url, err}
+}
+
+// affectedURLs returns the URLs of the pages that caused err
+func affectedURLs(err error) []string {
+	var urls []string
+	seen := map[string]bool{}
+	add := func(url string) {
+		if url != "" && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+
+	var walk func(err error)
+	walk = func(err error) {
+		var errs pool.Errors
+		var parseErrors ParseErrors
+		var parseError *ParseError
+		var pageError *PageError
+
+		switch {
+		case errors.As(err, &errs):
+			for _, e := range errs {
+				walk(e)
+			}
+		case errors.As(err, &parseErrors):
+			for _, e := range parseErrors {
+				add(e.URL)
+			}
+		case errors.As(err, &parseError):
+			add(parseError.URL)
+		case errors.As(err, &pageError):
+			add(pageError.URL)
+		}
+	}
+
+	walk(err)
+	return urls
+}
+
+// ParseError tells which field of a page could not be parsed
+type ParseError struct {
+	// URL of the page
+	URL string
+	// Index of the entry in the page, or -1 for the fields of the page itself
+	Index int
+	// Field that could not be parsed
+	Field string
+	// Raw text of the field
+	Raw string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: failed to parse %s %q: %s", e.URL, e.Field, e.Raw, e.Err)
+	}
+	return fmt.Sprintf("%s: entry %d: failed to parse %s %q: %s", e.URL, e.Index, e.Field, e.Raw, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors are the parse errors of a page. The entries parsed alongside
+// them are kept with the faulty fields left empty.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// err returns nil if there is no parse error
+func (e ParseErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
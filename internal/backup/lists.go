@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+var _ domain.ListsSource = (*Client)(nil)
+
+// listsPerPage is the number of lists shown per page of a user's lists
+const listsPerPage = 20
+
+func (c *Client) makeListsURL(username string, index int) string {
+	return c.baseURL + "/" + username + "/listes/all/all/all/page-" + strconv.Itoa(index)
+}
+
+// Lists returns the URLs of the lists of a user
+func (c *Client) Lists(ctx context.Context, username string) ([]string, error) {
+	url := c.makeListsURL(username, 1)
+
+	res, err := c.request(ctx, url)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	document, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	size := 0
+	if parsedValue := strings.TrimSpace(document.Find(".elli-lists-count").First().Text()); parsedValue != "" {
+		size, err = parseNumber(parsedValue)
+		if err != nil {
+			return nil, pageError(url, errors.Wrapf(err, "%s: failed to parse the number of lists", url))
+		}
+	}
+
+	urls := parseListURLs(document)
+
+	nbOfPages := int(math.Ceil(float64(size) / listsPerPage))
+	tasks := []*pool.Task{}
+	for i := 2; i <= nbOfPages; i++ {
+		pageURL := c.makeListsURL(username, i)
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			res, err := c.request(ctx, pageURL)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+
+			document, err := goquery.NewDocumentFromResponse(res)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+			return parseListURLs(document), nil
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	for _, task := range p.Tasks {
+		urls = append(urls, task.Out.([]string)...)
+	}
+
+	if len(urls) != size {
+		return nil, errors.Errorf("%d lists were found, but the user has %d", len(urls), size)
+	}
+	return urls, nil
+}
+
+// parseListURLs parses the URLs of the lists of a page
+func parseListURLs(document *goquery.Document) []string {
+	urls := make([]string, 0)
+	document.Find(".elli-lists-item a.elli-lists-title").Each(func(i int, s *goquery.Selection) {
+		if href, exists := s.Attr("href"); exists {
+			urls = append(urls, resolveURL(document, href))
+		}
+	})
+	return urls
+}
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/backend/mock"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// listPage returns a list page with one entry per ID
+func listPage(title string, ids ...string) string {
+	page := fmt.Sprintf(`<html><body><h1 class="d-heading1 elme-listTitle">%s</h1><span data-rel="list-products-count">%d</span><ul>`, title, len(ids))
+	for _, id := range ids {
+		page += `<li class="elli-item"><div class="elli-media"><figure data-sc-product-id="` + id + `"></figure></div></li>`
+	}
+	return page + `</ul></body></html>`
+}
+
+func TestLists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mlcdf":
+		case "/mlcdf/listes/all/all/all/page-1":
+			w.Write([]byte(`<html><body><h2>Listes <span class="elli-lists-count">(2)</span></h2><ul>` +
+				`<li class="elli-lists-item"><a class="elli-lists-title" href="/liste/Vu_au_cinema/363578">Vu au cinéma</a></li>` +
+				`<li class="elli-lists-item"><a class="elli-lists-title" href="/liste/Vu_en_2020/2583912">Vu en 2020</a></li>` +
+				`</ul></body></html>`))
+		case "/liste/Vu_au_cinema/363578":
+			w.Write([]byte(listPage("Vu au cinéma", "1", "2", "3")))
+		case "/liste/Vu_en_2020/2583912":
+			w.Write([]byte(listPage("Vu en 2020", "4")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+	back := mock.NewBackend()
+
+	summary, err := Lists(context.Background(), client, "mlcdf", back, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Saved) != 2 || len(summary.Missing) != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	for _, slug := range []string{"vu-au-cinema", "vu-en-2020"} {
+		if back.Data[slug] == nil {
+			t.Errorf("slug %s not found", slug)
+		}
+	}
+
+	index, ok := back.Data["index"].(*domain.ListIndex)
+	if !ok {
+		t.Fatalf("index not found")
+	}
+
+	if l := len(index.Lists); l != 2 {
+		t.Fatalf("expected 2 lists in the index, got %d", l)
+	}
+
+	expected := domain.ListInfo{Title: "Vu au cinéma", Slug: "vu-au-cinema", Entries: 3, URL: server.URL + "/liste/Vu_au_cinema/363578"}
+	if info := index.Lists[0]; *info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	if _, err := Lists(context.Background(), &fakeSource{}, "mlcdf", back, Options{}); err == nil {
+		t.Errorf("expected an error for a source that can't enumerate lists")
+	}
+}
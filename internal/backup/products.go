@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+var _ domain.ProductSource = (*Client)(nil)
+
+func (c *Client) makeProductURL(id string) string {
+	return c.baseURL + "/oeuvre/" + id
+}
+
+// Products fetches the page of each product
+func (c *Client) Products(ctx context.Context, ids []string) ([]*domain.Product, error) {
+	tasks := make([]*pool.Task, 0, len(ids))
+	for _, id := range ids {
+		url := c.makeProductURL(id)
+		id := id
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			res, err := c.request(ctx, url)
+			if err != nil {
+				return nil, pageError(url, err)
+			}
+
+			document, err := goquery.NewDocumentFromResponse(res)
+			if err != nil {
+				return nil, pageError(url, err)
+			}
+
+			product, err := parseProduct(document, id)
+			if err := c.checkParse(err); err != nil {
+				return nil, err
+			}
+			return product, nil
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+
+	products := make([]*domain.Product, 0, len(ids))
+	for _, task := range p.Tasks {
+		if product, ok := task.Out.(*domain.Product); ok && task.Err == nil {
+			products = append(products, product)
+		}
+	}
+	return products, p.Err()
+}
+
+// parseProduct parses the details of a product page. Fields that can't be
+// parsed are left empty and reported as ParseErrors.
+func parseProduct(document *goquery.Document, id string) (*domain.Product, error) {
+	var parseErrors ParseErrors
+	fail := func(field string, raw string, err error) {
+		parseErrors = append(parseErrors, &ParseError{documentURL(document), -1, field, raw, err})
+	}
+
+	product := &domain.Product{
+		ID:       id,
+		URL:      documentURL(document),
+		Synopsis: strings.TrimSpace(document.Find(".pvi-productDetails-resume").First().Text()),
+	}
+
+	if canonical, exists := document.Find("link[rel=canonical]").Attr("href"); exists {
+		product.URL = resolveURL(document, canonical)
+	}
+
+	if cover, exists := document.Find(`meta[property="og:image"]`).Attr("content"); exists {
+		product.CoverURL = resolveURL(document, cover)
+	}
+
+	document.Find("[itemprop=countryOfOrigin]").Each(func(i int, s *goquery.Selection) {
+		if country := strings.TrimSpace(s.Text()); country != "" {
+			product.Countries = append(product.Countries, country)
+		}
+	})
+
+	if duration := strings.TrimSpace(document.Find("[itemprop=duration]").First().Text()); duration != "" {
+		runtime, err := parseRuntime(duration)
+		if err != nil {
+			fail("runtime", duration, err)
+		}
+		product.Runtime = runtime
+	}
+
+	if rating := strings.TrimSpace(document.Find("[itemprop=ratingValue]").First().Text()); rating != "" {
+		average, err := strconv.ParseFloat(strings.Replace(rating, ",", ".", 1), 64)
+		if err != nil {
+			fail("average rating", rating, err)
+		}
+		product.AverageRating = average
+	}
+
+	return product, parseErrors.err()
+}
+
+var runtimeParts = regexp.MustCompile(`^(?:(\d+)\s*h)?\s*(?:(\d+)\s*min)?$`)
+
+// parseRuntime parses a runtime like "1 h 58 min" into minutes
+func parseRuntime(s string) (int, error) {
+	matches := runtimeParts.FindStringSubmatch(s)
+	if matches == nil || (matches[1] == "" && matches[2] == "") {
+		return 0, strconv.ErrSyntax
+	}
+
+	runtime := 0
+	if matches[1] != "" {
+		hours, _ := strconv.Atoi(matches[1])
+		runtime += hours * 60
+	}
+	if matches[2] != "" {
+		minutes, _ := strconv.Atoi(matches[2])
+		runtime += minutes
+	}
+	return runtime, nil
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/backend/mock"
+	"go.mlcdf.fr/sc-backup/internal/cache"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+const productPage = `<html><head><link rel="canonical" href="/film/Munich/388729">` +
+	`<meta property="og:image" content="https://media.senscritique.com/media/000006517378/300/munich.jpg"></head><body>` +
+	`<ul class="pvi-productDetails"><li><span itemprop="countryOfOrigin">États-Unis</span>, <span itemprop="countryOfOrigin">Canada</span></li>` +
+	`<li><span itemprop="duration">2 h 44 min</span></li></ul>` +
+	`<p class="pvi-productDetails-resume"> En 1972, lors des jeux Olympiques de Munich. </p>` +
+	`<span itemprop="ratingValue">7,4</span></body></html>`
+
+func TestProducts(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/mlcdf":
+		case "/oeuvre/388729":
+			w.Write([]byte(productPage))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	products, err := client.Products(context.Background(), []string{"388729", "404"})
+	if err == nil {
+		t.Errorf("expected an error for the missing product")
+	}
+	if l := len(products); l != 1 {
+		t.Fatalf("expected 1 product, got %d", l)
+	}
+
+	expected := domain.Product{
+		ID:            "388729",
+		URL:           server.URL + "/film/Munich/388729",
+		Runtime:       164,
+		Synopsis:      "En 1972, lors des jeux Olympiques de Munich.",
+		CoverURL:      "https://media.senscritique.com/media/000006517378/300/munich.jpg",
+		AverageRating: 7.4,
+	}
+	product := products[0]
+	if len(product.Countries) != 2 || product.Countries[1] != "Canada" {
+		t.Errorf("unexpected countries %v", product.Countries)
+	}
+	product.Countries = nil
+	if !reflect.DeepEqual(*product, expected) {
+		t.Errorf("expected %+v, got %+v", expected, product)
+	}
+
+	// enrich a collection twice, the second time from the cache
+	src := &enrichingSource{Client: client, collection: []*domain.Entry{{ID: "388729", Title: "Munich"}}}
+	cachePath := filepath.Join(t.TempDir(), cache.Filename)
+	opts := Options{Categories: []string{"films"}, Filters: []string{"wish"}, Enrich: true, Products: cache.New(cachePath)}
+
+	for i := 0; i < 2; i++ {
+		back := mock.NewBackend()
+		if _, err := Collection(context.Background(), src, "mlcdf", back, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		entry := back.Data["films-wish"].(*domain.Collection).Entries[0]
+		if entry.Details == nil || entry.Details.Runtime != 164 {
+			t.Errorf("expected the entry to be enriched, got %+v", entry.Details)
+		}
+	}
+
+	if requests["/oeuvre/388729"] != 2 {
+		t.Errorf("the product should have been fetched once by the enrichment, got %d requests", requests["/oeuvre/388729"]-1)
+	}
+
+	if cached, err := cache.Load(cachePath); err != nil || cached.Len() != 1 {
+		t.Errorf("expected the product to be saved in the cache")
+	}
+}
+
+// enrichingSource serves a collection and fetches the products with Client
+type enrichingSource struct {
+	*Client
+	collection []*domain.Entry
+}
+
+func (e *enrichingSource) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	entries := make([]*domain.Entry, 0, len(e.collection))
+	for _, entry := range e.collection {
+		clone := *entry
+		entries = append(entries, &clone)
+	}
+	return domain.NewCollection(entries, category, filter, username), nil
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+var _ domain.ReviewSource = (*Client)(nil)
+
+// reviewsPerPage is the number of reviews listed per page
+const reviewsPerPage = 10
+
+func (c *Client) makeReviewsURL(username string, index int) string {
+	url := c.baseURL + "/" + username + "/critiques"
+	if index > 1 {
+		url += "/page-" + strconv.Itoa(index)
+	}
+	return url
+}
+
+// Reviews fetches the pages listing the reviews of a user, then the page of
+// each review for its full body
+func (c *Client) Reviews(ctx context.Context, username string) (*domain.Reviews, error) {
+	url := c.makeReviewsURL(username, 1)
+
+	res, err := c.request(ctx, url)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	document, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	size, err := reviewsSize(document)
+	if err != nil {
+		return nil, pageError(url, errors.Wrapf(err, "%s", url))
+	}
+
+	reviews, err := parseReviewList(document)
+	if err := c.checkParse(err); err != nil {
+		return nil, err
+	}
+
+	nbOfPages := int(math.Ceil(float64(size) / reviewsPerPage))
+	tasks := []*pool.Task{}
+	for i := 2; i <= nbOfPages; i++ {
+		pageURL := c.makeReviewsURL(username, i)
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			res, err := c.request(ctx, pageURL)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+
+			document, err := goquery.NewDocumentFromResponse(res)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+
+			reviews, err := parseReviewList(document)
+			if err := c.checkParse(err); err != nil {
+				return nil, err
+			}
+			return reviews, nil
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	for _, task := range p.Tasks {
+		reviews = append(reviews, task.Out.([]*domain.Review)...)
+	}
+
+	if len(reviews) != size {
+		return nil, errors.Errorf("%d reviews were found, but the user has %d", len(reviews), size)
+	}
+
+	// the listing only shows an excerpt of the reviews
+	tasks = []*pool.Task{}
+	for _, review := range reviews {
+		if review.URL == "" {
+			continue
+		}
+		review := review
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			return nil, c.fetchReview(ctx, review)
+		}))
+	}
+
+	p = pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+
+	return domain.NewReviews(reviews, username), nil
+}
+
+// fetchReview completes a review with its body, date and likes
+func (c *Client) fetchReview(ctx context.Context, review *domain.Review) error {
+	res, err := c.request(ctx, review.URL)
+	if err != nil {
+		return pageError(review.URL, err)
+	}
+
+	document, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return pageError(review.URL, err)
+	}
+
+	return c.checkParse(parseReview(document, review))
+}
+
+// reviewsSize parses the number of reviews written by the user
+func reviewsSize(document *goquery.Document) (int, error) {
+	parsedValue := strings.TrimSpace(document.Find(".ere-reviews-count").First().Text())
+	if parsedValue == "" {
+		return 0, nil
+	}
+	return parseNumber(parsedValue)
+}
+
+// parseReviewList parses the reviews of a listing page. Only their IDs,
+// product IDs, titles and URLs are set.
+func parseReviewList(document *goquery.Document) ([]*domain.Review, error) {
+	reviews := make([]*domain.Review, 0)
+	var parseErrors ParseErrors
+
+	document.Find(".ere-review").Each(func(i int, s *goquery.Selection) {
+		review := &domain.Review{}
+		review.ID, _ = s.Attr("data-sc-review-id")
+		review.ProductID, _ = s.Find("[data-sc-product-id]").Attr("data-sc-product-id")
+		review.Title = strings.TrimSpace(s.Find(".ere-review-title").Text())
+
+		href, exists := s.Find("a.ere-review-anchor").Attr("href")
+		if exists {
+			review.URL = resolveURL(document, href)
+		} else {
+			parseErrors = append(parseErrors, &ParseError{documentURL(document), i, "review URL", "", errors.New("missing link to the review")})
+		}
+
+		reviews = append(reviews, review)
+	})
+
+	return reviews, parseErrors.err()
+}
+
+// parseReview parses the page of a review
+func parseReview(document *goquery.Document, review *domain.Review) error {
+	var parseErrors ParseErrors
+
+	content := document.Find(".rvi-review-content").First()
+	html, err := content.Html()
+	if err != nil {
+		parseErrors = append(parseErrors, &ParseError{review.URL, -1, "review body", "", err})
+	}
+	review.HTML = strings.TrimSpace(html)
+	review.Text = plainText(content)
+
+	review.Date, _ = document.Find("time.rvi-review-date").Attr("datetime")
+
+	if likes := strings.TrimSpace(document.Find(".rvi-likes-count").First().Text()); likes != "" {
+		review.Likes, err = parseNumber(likes)
+		if err != nil {
+			parseErrors = append(parseErrors, &ParseError{review.URL, -1, "likes", likes, err})
+		}
+	}
+
+	return parseErrors.err()
+}
+
+// resolveURL resolves href against the URL the document was fetched from
+func resolveURL(document *goquery.Document, href string) string {
+	if document.Url == nil {
+		return href
+	}
+	url, err := document.Url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return url.String()
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// plainText returns the text of an HTML body, with its paragraphs separated
+// by blank lines
+func plainText(s *goquery.Selection) string {
+	s = s.Clone()
+	s.Find("br").ReplaceWithHtml("\n")
+	s.Find("p, blockquote, li, h2, h3").Each(func(i int, s *goquery.Selection) {
+		s.AppendHtml("\n\n")
+	})
+
+	lines := strings.Split(s.Text(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,72 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// reviewListPage returns a page listing the reviews with the given IDs
+func reviewListPage(count int, ids ...int) string {
+	page := fmt.Sprintf(`<html><body><h2>Critiques <span class="ere-reviews-count">(%d)</span></h2><ul>`, count)
+	for _, id := range ids {
+		page += fmt.Sprintf(`<li class="ere-review" data-sc-review-id="%d"><figure data-sc-product-id="%d"></figure>`+
+			`<h3 class="ere-review-title">Critique %d</h3><a class="ere-review-anchor" href="/film/critique/%d">Lire la critique</a></li>`, id, id+1000, id, id)
+	}
+	return page + `</ul></body></html>`
+}
+
+func TestReviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/mlcdf/critiques":
+			w.Write([]byte(reviewListPage(11, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)))
+		case r.URL.Path == "/mlcdf/critiques/page-2":
+			w.Write([]byte(reviewListPage(11, 11)))
+		case strings.HasPrefix(r.URL.Path, "/film/critique/"):
+			w.Write([]byte(`<html><body><time class="rvi-review-date" datetime="2020-12-04">4 déc. 2020</time>` +
+				`<div class="rvi-review-content"><p>Un <b>chef-d'œuvre</b>.<br>Vraiment.</p><p>À revoir.</p></div>` +
+				`<span class="rvi-likes-count">12</span></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+
+	reviews, err := client.Reviews(context.Background(), "mlcdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reviews.Slug() != "reviews" {
+		t.Errorf("expected slug reviews, got %s", reviews.Slug())
+	}
+
+	if l := len(reviews.Reviews); l != 11 {
+		t.Fatalf("expected 11 reviews, got %d", l)
+	}
+
+	review := reviews.Reviews[10]
+	if review.ID != "11" || review.ProductID != "1011" || review.Title != "Critique 11" {
+		t.Errorf("unexpected review %+v", review)
+	}
+	if review.URL != server.URL+"/film/critique/11" {
+		t.Errorf("unexpected URL %s", review.URL)
+	}
+	if review.Date != "2020-12-04" || review.Likes != 12 {
+		t.Errorf("expected date 2020-12-04 and 12 likes, got %s and %d", review.Date, review.Likes)
+	}
+	if !strings.Contains(review.HTML, "<b>chef-d&#39;œuvre</b>") {
+		t.Errorf("unexpected HTML %s", review.HTML)
+	}
+	if expected := "Un chef-d'œuvre.\nVraiment.\n\nÀ revoir."; review.Text != expected {
+		t.Errorf("expected text %q, got %q", expected, review.Text)
+	}
+}
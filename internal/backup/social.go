@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+)
+
+var _ domain.SocialSource = (*Client)(nil)
+
+// peoplePerPage is the number of users shown per page of scouts or followers
+const peoplePerPage = 24
+
+func (c *Client) makePeopleURL(username string, relation string, index int) string {
+	return c.baseURL + "/" + username + "/" + relation + "/page-" + strconv.Itoa(index)
+}
+
+// SocialGraph fetches the scouts and the followers of a user
+func (c *Client) SocialGraph(ctx context.Context, username string) (*domain.SocialGraph, error) {
+	scouts, err := c.people(ctx, username, "eclaireurs")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch the scouts")
+	}
+
+	followers, err := c.people(ctx, username, "abonnes")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch the followers")
+	}
+
+	return domain.NewSocialGraph(scouts, followers, username), nil
+}
+
+// people fetches the users listed on the pages of relation
+func (c *Client) people(ctx context.Context, username string, relation string) ([]*domain.Person, error) {
+	url := c.makePeopleURL(username, relation, 1)
+
+	res, err := c.request(ctx, url)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	document, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return nil, pageError(url, err)
+	}
+
+	size := 0
+	if parsedValue := strings.TrimSpace(document.Find(".elgr-count").First().Text()); parsedValue != "" {
+		size, err = parseNumber(parsedValue)
+		if err != nil {
+			return nil, pageError(url, errors.Wrapf(err, "%s: failed to parse the number of users", url))
+		}
+	}
+
+	people := parsePeople(document)
+
+	nbOfPages := int(math.Ceil(float64(size) / peoplePerPage))
+	tasks := []*pool.Task{}
+	for i := 2; i <= nbOfPages; i++ {
+		pageURL := c.makePeopleURL(username, relation, i)
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			res, err := c.request(ctx, pageURL)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+
+			document, err := goquery.NewDocumentFromResponse(res)
+			if err != nil {
+				return nil, pageError(pageURL, err)
+			}
+			return parsePeople(document), nil
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	for _, task := range p.Tasks {
+		people = append(people, task.Out.([]*domain.Person)...)
+	}
+
+	if len(people) != size {
+		return nil, errors.Errorf("%d users were found, but there should be %d", len(people), size)
+	}
+	return people, nil
+}
+
+// parsePeople parses the users listed on a page
+func parsePeople(document *goquery.Document) []*domain.Person {
+	people := make([]*domain.Person, 0)
+	document.Find(".elgr-user a.elgr-user-name").Each(func(i int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists {
+			return
+		}
+		people = append(people, &domain.Person{
+			Username:    path.Base(strings.TrimSuffix(href, "/")),
+			DisplayName: strings.TrimSpace(s.Text()),
+			URL:         resolveURL(document, href),
+		})
+	})
+	return people
+}
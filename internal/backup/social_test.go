@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/backend/mock"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// peoplePage returns a page listing the given users
+func peoplePage(count int, usernames ...string) string {
+	page := fmt.Sprintf(`<html><body><h2>Éclaireurs <span class="elgr-count">(%d)</span></h2><ul>`, count)
+	for _, username := range usernames {
+		page += fmt.Sprintf(`<li class="elgr-user"><a class="elgr-user-name" href="/%s"> %s </a></li>`, username, "Display "+username)
+	}
+	return page + `</ul></body></html>`
+}
+
+func TestSocialGraph(t *testing.T) {
+	scouts := make([]string, 25)
+	for i := range scouts {
+		scouts[i] = fmt.Sprint("scout", i)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mlcdf":
+		case "/mlcdf/eclaireurs/page-1":
+			w.Write([]byte(peoplePage(25, scouts[:24]...)))
+		case "/mlcdf/eclaireurs/page-2":
+			w.Write([]byte(peoplePage(25, scouts[24:]...)))
+		case "/mlcdf/abonnes/page-1":
+			w.Write([]byte(peoplePage(1, "follower")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(WithBaseURL(server.URL), WithRetryPolicy(retry.Policy{MaxAttempts: 1}))
+	back := mock.NewBackend()
+
+	_, err := Collection(context.Background(), client, "mlcdf", back, Options{Filters: []string{"wish"}, Social: true, KeepGoing: true})
+	if err != ErrIncomplete {
+		t.Fatalf("expected the collections to fail, got %v", err)
+	}
+
+	graph, ok := back.Data["social"].(*domain.SocialGraph)
+	if !ok {
+		t.Fatalf("social not found")
+	}
+
+	if l := len(graph.Scouts); l != 25 {
+		t.Fatalf("expected 25 scouts, got %d", l)
+	}
+
+	expected := domain.Person{Username: "scout24", DisplayName: "Display scout24", URL: server.URL + "/scout24"}
+	if scout := graph.Scouts[24]; *scout != expected {
+		t.Errorf("expected %+v, got %+v", expected, scout)
+	}
+
+	if l := len(graph.Followers); l != 1 || graph.Followers[0].Username != "follower" {
+		t.Errorf("unexpected followers %v", graph.Followers)
+	}
+
+	if records := graph.Records(); len(records) != 26 || records[25][0] != "follower" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
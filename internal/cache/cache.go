@@ -0,0 +1,101 @@
+// Package cache keeps the details of the products on disk, so that an
+// enriched backup only fetches the products it hasn't seen before.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.mlcdf.fr/sc-backup/internal/backend"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+// Filename is the name of the cache file in the output directory
+const Filename = ".products.json"
+
+// Cache holds the details of the products by ID. A nil Cache holds nothing.
+type Cache struct {
+	path string
+
+	mu       sync.Mutex
+	Products map[string]*domain.Product `json:"products"`
+}
+
+// New returns an empty cache saved at path
+func New(path string) *Cache {
+	return &Cache{
+		path:     path,
+		Products: map[string]*domain.Product{},
+	}
+}
+
+// Load reads the cache saved at path. It returns an empty cache if there is
+// none.
+func Load(path string) (*Cache, error) {
+	c := New(path)
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("failed to decode cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Get returns the details of a product
+func (c *Cache) Get(id string) (*domain.Product, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	product, ok := c.Products[id]
+	return product, ok
+}
+
+// Set records the details of a product. Call Save to write them to disk.
+func (c *Cache) Set(product *domain.Product) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Products[product.ID] = product
+}
+
+// Len returns the number of cached products
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Products)
+}
+
+// Save writes the cache to disk
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return backend.WriteFile(c.path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c)
+	})
+}
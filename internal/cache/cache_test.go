@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), Filename)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("a new cache should be empty")
+	}
+
+	c.Set(&domain.Product{ID: "388729", Runtime: 164, Countries: []string{"États-Unis"}})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product, ok := c.Get("388729")
+	if !ok || product.Runtime != 164 || product.Countries[0] != "États-Unis" {
+		t.Errorf("unexpected product %+v", product)
+	}
+
+	if _, ok := c.Get("491576"); ok {
+		t.Errorf("491576 was not cached")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Set(&domain.Product{ID: "388729"})
+	if _, ok := c.Get("388729"); ok {
+		t.Errorf("a nil cache should hold nothing")
+	}
+	if err := c.Save(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,185 @@
+// Package cassette records the HTTP responses received by a client and
+// replays them, so that the scrapers can be tested offline.
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mlcdf.fr/sc-backup/internal/backend"
+)
+
+// Mode tells a Cassette whether to hit the network or not
+type Mode int
+
+const (
+	// Replay serves the recorded responses and never hits the network
+	Replay Mode = iota
+	// Record forwards the requests and records the responses
+	Record
+)
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type file struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+var _ http.RoundTripper = (*Cassette)(nil)
+
+// Cassette is an http.RoundTripper that records or replays interactions
+type Cassette struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions map[string]*Interaction
+}
+
+// New loads the cassette stored at path. In Record mode, requests are sent
+// through transport, or http.DefaultTransport if nil, and the cassette
+// starts empty.
+func New(path string, mode Mode, transport http.RoundTripper) (*Cassette, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	c := &Cassette{
+		path:         path,
+		mode:         mode,
+		transport:    transport,
+		interactions: map[string]*Interaction{},
+	}
+
+	if mode == Record {
+		return c, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+
+	for _, interaction := range f.Interactions {
+		c.interactions[key(interaction.Method, interaction.URL)] = interaction
+	}
+	return c, nil
+}
+
+func key(method string, url string) string {
+	return method + " " + url
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.mode == Record {
+		return c.record(req)
+	}
+
+	c.mu.Lock()
+	interaction, ok := c.interactions[key(req.Method, req.URL.String())]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("cassette %s: no recorded response for %s %s", c.path, req.Method, req.URL)
+	}
+	return interaction.response(req), nil
+}
+
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	res, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: res.StatusCode,
+		Header: http.Header{},
+		Body:   string(body),
+	}
+	for _, name := range []string{"Content-Type", "Location", "Retry-After"} {
+		if value := res.Header.Get(name); value != "" {
+			interaction.Header.Set(name, value)
+		}
+	}
+
+	c.mu.Lock()
+	c.interactions[key(req.Method, interaction.URL)] = interaction
+	c.mu.Unlock()
+
+	return interaction.response(req), nil
+}
+
+func (i *Interaction) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	for name, values := range i.Header {
+		header[name] = append([]string(nil), values...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op
+// in Replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != Record {
+		return nil
+	}
+
+	c.mu.Lock()
+	f := file{Interactions: make([]*Interaction, 0, len(c.interactions))}
+	for _, interaction := range c.interactions {
+		f.Interactions = append(f.Interactions, interaction)
+	}
+	c.mu.Unlock()
+
+	// sort the interactions to keep the diffs readable when recording again
+	sort.Slice(f.Interactions, func(i, j int) bool {
+		return key(f.Interactions[i].Method, f.Interactions[i].URL) < key(f.Interactions[j].Method, f.Interactions[j].URL)
+	})
+
+	// an interrupted recording keeps the previous cassette
+	return backend.WriteFile(c.path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(&f)
+	})
+}
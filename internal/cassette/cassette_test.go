@@ -0,0 +1,85 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Write([]byte("<p>" + r.URL.Path + "</p>"))
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+	noRedirect := func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	recorder, err := New(path, Record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: recorder, CheckRedirect: noRedirect}
+	get(t, client, server.URL+"/mlcdf")
+	get(t, client, server.URL+"/moved")
+
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	player, err := New(path, Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: player, CheckRedirect: noRedirect}
+
+	res, body := get(t, client, server.URL+"/mlcdf")
+	if body != "<p>/mlcdf</p>" {
+		t.Errorf("unexpected body %s", body)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/html; charset=UTF-8" {
+		t.Errorf("unexpected Content-Type %s", ct)
+	}
+	if res.Request.URL.Path != "/mlcdf" {
+		t.Errorf("the response should reference its request")
+	}
+
+	res, _ = get(t, client, server.URL+"/moved")
+	if res.StatusCode != http.StatusMovedPermanently || res.Header.Get("Location") != "/" {
+		t.Errorf("expected a redirection to /, got %d %s", res.StatusCode, res.Header.Get("Location"))
+	}
+
+	if _, err := client.Get(server.URL + "/unknown"); err == nil {
+		t.Errorf("expected an error for a request missing from the cassette")
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), Replay, nil); err == nil {
+		t.Errorf("expected an error for a missing cassette")
+	}
+}
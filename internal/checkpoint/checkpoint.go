@@ -0,0 +1,104 @@
+// Package checkpoint records the pages fetched during a backup, so that an
+// interrupted or failed backup can be resumed without fetching them again.
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.mlcdf.fr/sc-backup/internal/backend"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+// Dirname is the name of the checkpoint directory in the output directory
+const Dirname = ".checkpoint"
+
+// sizeName is the name of the file holding the number of entries of a
+// collection, next to its pages
+const sizeName = "size"
+
+// Checkpoint holds the number of entries and the parsed pages of each
+// collection, in a file per page so that recording a page doesn't rewrite
+// the others. A nil Checkpoint records nothing.
+type Checkpoint struct {
+	dir string
+}
+
+// New returns the checkpoint saved in dir. The pages recorded by a previous
+// run are read on demand.
+func New(dir string) *Checkpoint {
+	return &Checkpoint{dir: dir}
+}
+
+// PageKey identifies a page of a collection, e.g. films-done/3
+func PageKey(slug string, page int) string {
+	return fmt.Sprintf("%s/%d", slug, page)
+}
+
+// Size returns the number of entries recorded for a collection
+func (c *Checkpoint) Size(slug string) (int, bool) {
+	var size int
+	if !c.read(slug+"/"+sizeName, &size) {
+		return 0, false
+	}
+	return size, true
+}
+
+// Page returns the entries recorded for a page
+func (c *Checkpoint) Page(key string) ([]*domain.Entry, bool) {
+	var entries []*domain.Entry
+	if !c.read(key, &entries) {
+		return nil, false
+	}
+	return entries, true
+}
+
+// SetSize records the number of entries of a collection
+func (c *Checkpoint) SetSize(slug string, size int) error {
+	return c.write(slug+"/"+sizeName, size)
+}
+
+// SetPage records the entries of a page
+func (c *Checkpoint) SetPage(key string, entries []*domain.Entry) error {
+	return c.write(key, entries)
+}
+
+// Remove deletes the checkpoint, once the backup is complete
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	return os.RemoveAll(c.dir)
+}
+
+func (c *Checkpoint) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key)+".json")
+}
+
+// read decodes the file of key into v. A missing or corrupted file is
+// reported as missing, so that its page is fetched again.
+func (c *Checkpoint) read(key string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	content, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(content, v) == nil
+}
+
+// write saves v as the file of key
+func (c *Checkpoint) write(key string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	return backend.WriteFile(c.path(key), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+}
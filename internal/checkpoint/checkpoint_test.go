@@ -0,0 +1,64 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mlcdf", Dirname)
+
+	c := New(dir)
+	if _, ok := c.Size("films-done"); ok {
+		t.Errorf("a new checkpoint should be empty")
+	}
+
+	if err := c.SetSize("films-done", 19); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetPage(PageKey("films-done", 2), []*domain.Entry{{ID: "388729", Title: "Munich"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	c = New(dir)
+	if size, ok := c.Size("films-done"); !ok || size != 19 {
+		t.Errorf("expected size 19, got %d", size)
+	}
+
+	entries, ok := c.Page("films-done/2")
+	if !ok || len(entries) != 1 || entries[0].Title != "Munich" {
+		t.Errorf("unexpected page %v", entries)
+	}
+
+	if _, ok := c.Page("films-done/1"); ok {
+		t.Errorf("page 1 was not recorded")
+	}
+
+	// a page interrupted while being written is fetched again
+	if err := os.WriteFile(filepath.Join(dir, "films-done", "3.json"), []byte(`[{"id":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Page("films-done/3"); ok {
+		t.Errorf("a corrupted page should be missing")
+	}
+
+	if err := c.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("the checkpoint should be removed")
+	}
+}
+
+func TestNilCheckpoint(t *testing.T) {
+	var c *Checkpoint
+	if err := c.SetPage("films-done/1", nil); err != nil {
+		t.Error(err)
+	}
+	if _, ok := c.Page("films-done/1"); ok {
+		t.Errorf("a nil checkpoint should not record anything")
+	}
+}
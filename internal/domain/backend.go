@@ -1,11 +1,40 @@
 package domain
 
+import "io"
+
 type Serializable interface {
 	Slug() string
 	CSV() []*Entry
 	JSON() interface{}
 }
 
+// Table is implemented by the Serializables that are not made of entries,
+// to be formatted as CSV
+type Table interface {
+	Serializable
+
+	// Header returns the names of the columns
+	Header() []string
+	// Records returns the rows, in the order of Header
+	Records() [][]string
+}
+
+// Field is a named value
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Described is implemented by the Serializables whose entries share
+// metadata, like the category of a collection, to be formatted as CSV
+// columns
+type Described interface {
+	Serializable
+
+	// Metadata returns the fields shared by the entries
+	Metadata() []Field
+}
+
 type Backend interface {
 	// Location returns this backend's location (the directory name).
 	Location() string
@@ -14,4 +43,15 @@ type Backend interface {
 	Create() error
 
 	Save(Serializable) error
+
+	// Load reads the data previously saved under the slug of data into it.
+	// It returns an error wrapping os.ErrNotExist if there is none.
+	Load(data Serializable) error
+
+	// HasAsset tells whether an asset, like an image, was saved under name
+	HasAsset(name string) bool
+
+	// SaveAsset saves the content of reader under name, a slash-separated
+	// path relative to the location of the backend
+	SaveAsset(name string, reader io.Reader) error
 }
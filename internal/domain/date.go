@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Precision is the most precise part known of a PartialDate
+type Precision int
+
+const (
+	PrecisionNone Precision = iota
+	PrecisionYear
+	PrecisionMonth
+	PrecisionDay
+)
+
+// PartialDate is a date whose month and day may be unknown, like the dates
+// of the journal. The unknown parts are 0.
+type PartialDate struct {
+	Year      int
+	Month     int
+	Day       int
+	Precision Precision
+}
+
+// NewPartialDate returns a date, precise up to the first part which is 0
+func NewPartialDate(year, month, day int) PartialDate {
+	switch {
+	case year == 0:
+		return PartialDate{}
+	case month == 0:
+		return PartialDate{Year: year, Precision: PrecisionYear}
+	case day == 0:
+		return PartialDate{Year: year, Month: month, Precision: PrecisionMonth}
+	}
+	return PartialDate{Year: year, Month: month, Day: day, Precision: PrecisionDay}
+}
+
+// ParsePartialDate parses a date like "2020", "2020-12" or "2020-12-04". The
+// unknown parts may also be zeros, like in "2020-00-00".
+func ParsePartialDate(s string) (PartialDate, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) > 3 {
+		return PartialDate{}, fmt.Errorf("invalid date %q", s)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return PartialDate{}, fmt.Errorf("invalid date %q", s)
+		}
+		numbers[i] = n
+	}
+
+	year, month, day := numbers[0], numbers[1], numbers[2]
+	if month > 12 || day > 31 || (month == 0 && day != 0) {
+		return PartialDate{}, fmt.Errorf("invalid date %q", s)
+	}
+	return NewPartialDate(year, month, day), nil
+}
+
+// IsZero reports whether the date is unknown
+func (d PartialDate) IsZero() bool {
+	return d.Precision == PrecisionNone
+}
+
+// String returns the date in the ISO 8601 format, without its unknown parts
+func (d PartialDate) String() string {
+	switch d.Precision {
+	case PrecisionYear:
+		return fmt.Sprintf("%04d", d.Year)
+	case PrecisionMonth:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	case PrecisionDay:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+	return ""
+}
+
+// Compare returns -1, 0 or 1 if d is before, the same as or after other. An
+// unknown part is before the known ones, so 2020 is before 2020-01.
+func (d PartialDate) Compare(other PartialDate) int {
+	for _, parts := range [][2]int{{d.Year, other.Year}, {d.Month, other.Month}, {d.Day, other.Day}} {
+		if parts[0] < parts[1] {
+			return -1
+		}
+		if parts[0] > parts[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Before reports whether d is before other
+func (d PartialDate) Before(other PartialDate) bool {
+	return d.Compare(other) < 0
+}
+
+// Columns returns the year, month and day of the date for a CSV file. The
+// unknown parts are empty.
+func (d PartialDate) Columns() []string {
+	columns := make([]string, 3)
+	for i, part := range []int{d.Year, d.Month, d.Day} {
+		if int(d.Precision) > i {
+			columns[i] = strconv.Itoa(part)
+		}
+	}
+	return columns
+}
+
+func (d PartialDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON also accepts the dates of the previous backups, like
+// "2020-00-00"
+func (d *PartialDate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = PartialDate{}
+		return nil
+	}
+
+	date, err := ParsePartialDate(s)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParsePartialDate(t *testing.T) {
+	tests := []struct {
+		raw       string
+		expected  string
+		precision Precision
+	}{
+		{"2020-12-04", "2020-12-04", PrecisionDay},
+		{"2020-12-00", "2020-12", PrecisionMonth},
+		{"2020-00-00", "2020", PrecisionYear},
+		{"2020-12", "2020-12", PrecisionMonth},
+		{"2020", "2020", PrecisionYear},
+		{"0000-00-00", "", PrecisionNone},
+	}
+
+	for _, tt := range tests {
+		date, err := ParsePartialDate(tt.raw)
+		if err != nil {
+			t.Errorf("%s: %s", tt.raw, err)
+			continue
+		}
+		if date.String() != tt.expected || date.Precision != tt.precision {
+			t.Errorf("%s: expected %s with precision %d, got %s with precision %d", tt.raw, tt.expected, tt.precision, date, date.Precision)
+		}
+	}
+
+	for _, raw := range []string{"", "2020-13-01", "2020-00-04", "2020-12-04-01", "12/04/2020"} {
+		if _, err := ParsePartialDate(raw); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
+
+func TestPartialDateSort(t *testing.T) {
+	dates := []PartialDate{}
+	for _, raw := range []string{"2020-12-04", "2019", "2020-12", "2020-01-31", "2020"} {
+		date, _ := ParsePartialDate(raw)
+		dates = append(dates, date)
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	sorted := make([]string, 0, len(dates))
+	for _, date := range dates {
+		sorted = append(sorted, date.String())
+	}
+	if s := strings.Join(sorted, ","); s != "2019,2020,2020-01-31,2020-12,2020-12-04" {
+		t.Errorf("unexpected order %s", s)
+	}
+
+	if dates[1].Compare(NewPartialDate(2020, 0, 0)) != 0 {
+		t.Errorf("expected %s to be the same as 2020", dates[1])
+	}
+}
+
+func TestPartialDateJSON(t *testing.T) {
+	var entry Entry
+	if err := json.Unmarshal([]byte(`{"done_date": "2020-12-00"}`), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.DoneDate == nil || *entry.DoneDate != NewPartialDate(2020, 12, 0) {
+		t.Fatalf("unexpected done date %v", entry.DoneDate)
+	}
+
+	content, err := json.Marshal(&Episode{WatchedDate: entry.DoneDate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"watched_date":"2020-12"`) {
+		t.Errorf("unexpected JSON %s", content)
+	}
+
+	if columns := strings.Join(entry.DoneDate.Columns(), ","); columns != "2020,12," {
+		t.Errorf("unexpected columns %s", columns)
+	}
+}
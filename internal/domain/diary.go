@@ -0,0 +1,51 @@
+package domain
+
+// DiaryEvent is a product marked as done in the journal of a user. A
+// product done several times, like a rewatched movie, has an event per time.
+type DiaryEvent struct {
+	Date      PartialDate `json:"date"`
+	ProductID string      `json:"product_id"`
+	// Category is empty if the product is in none of the backed up
+	// collections
+	Category string `json:"category,omitempty"`
+}
+
+var _ Table = (*Diary)(nil)
+
+// Diary is the timeline of the journal of a user
+type Diary struct {
+	Events   []*DiaryEvent `json:"events"`
+	Username string        `json:"username"`
+}
+
+func NewDiary(events []*DiaryEvent, Username string) *Diary {
+	return &Diary{
+		Events:   events,
+		Username: Username,
+	}
+}
+
+func (d *Diary) Slug() string {
+	return "diary"
+}
+
+// CSV returns nil: the diary is not made of entries, see Records
+func (d *Diary) CSV() []*Entry {
+	return nil
+}
+
+func (d *Diary) JSON() interface{} {
+	return d
+}
+
+func (d *Diary) Header() []string {
+	return []string{"year", "month", "day", "product_id", "category"}
+}
+
+func (d *Diary) Records() [][]string {
+	records := make([][]string, 0, len(d.Events))
+	for _, event := range d.Events {
+		records = append(records, append(event.Date.Columns(), event.ProductID, event.Category))
+	}
+	return records
+}
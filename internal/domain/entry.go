@@ -2,34 +2,79 @@ package domain
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/metal3d/go-slugify"
 )
 
+// The states of an entry in a user's collection. They are used as the
+// collection filters.
+const (
+	StateDone       = "done"
+	StateWish       = "wish"
+	StateInProgress = "in-progress"
+)
+
 // Entry represents an entry in a collection or list : a movie, series, books, etc...
 type Entry struct {
-	ID            string   `json:"id"`
-	Title         string   `json:"title"`
-	OriginalTitle string   `json:"original_title,omitempty"`
-	Year          int      `json:"year,omitempty"`
-	Authors       []string `json:"authors"`
-	Rating        int      `json:"rating,omitempty"`
-	DoneDate      string   `json:"done_date,omitempty"`
-	Comment       string   `json:"comment,omitempty"`
-	Favorite      bool     `json:"favorite"`
-	Genres        []string `json:"genres,omitempty"`
+	ID            string       `json:"id"`
+	Title         string       `json:"title"`
+	OriginalTitle string       `json:"original_title,omitempty"`
+	Year          int          `json:"year,omitempty"`
+	Authors       []string     `json:"authors"`
+	Rating        int          `json:"rating,omitempty"`
+	DoneDate      *PartialDate `json:"done_date,omitempty"`
+	Comment       string       `json:"comment,omitempty"`
+	Favorite      bool         `json:"favorite"`
+	Genres        []string     `json:"genres,omitempty"`
+	// DoneDates are all the dates of the journal, from the oldest to the
+	// most recent one which is DoneDate
+	DoneDates []PartialDate `json:"done_dates,omitempty"`
+	// State is one of the State constants, empty for the entries of a list
+	State string `json:"state,omitempty"`
+	// Platforms are the platforms of a video game
+	Platforms []string `json:"platforms,omitempty"`
+	// Episodes are the watched episodes of a series
+	Episodes []*Episode `json:"episodes,omitempty"`
+	// Details are only set by an enriched backup
+	Details *Product `json:"details,omitempty"`
+	// Poster is the URL of the poster, and PosterPath the path of its copy
+	// relative to the backup files
+	Poster     string `json:"poster,omitempty"`
+	PosterPath string `json:"poster_path,omitempty"`
+}
+
+// Episode is a watched episode of a series
+type Episode struct {
+	ID          string       `json:"id"`
+	SeriesID    string       `json:"series_id"`
+	Season      int          `json:"season,omitempty"`
+	Number      int          `json:"number,omitempty"`
+	WatchedDate *PartialDate `json:"watched_date,omitempty"`
 }
 
-var _ Serializable = (*Collection)(nil)
+var _ Described = (*Collection)(nil)
 
 type Collection struct {
 	Entries  []*Entry `json:"entries"`
 	Category string   `json:"category"`
-	Filter   string   `json:"filter"`
-	Username string   `json:"username"`
+	// Filter is the state of the entries of the collection
+	Filter   string `json:"filter"`
+	Username string `json:"username"`
+	// FullBackupAt is when all the pages of the collection were last
+	// fetched. Incremental backups rely on it to schedule a full one.
+	FullBackupAt *time.Time `json:"full_backup_at,omitempty"`
 }
 
+// NewCollection returns a collection. The entries without a state are set
+// to the state of the collection.
 func NewCollection(entries []*Entry, Category, Filter, Username string) *Collection {
+	for _, entry := range entries {
+		if entry.State == "" {
+			entry.State = Filter
+		}
+	}
+
 	return &Collection{
 		Entries:  entries,
 		Category: Category,
@@ -50,7 +95,20 @@ func (c *Collection) JSON() interface{} {
 	return c
 }
 
-var _ Serializable = (*List)(nil)
+func (c *Collection) Metadata() []Field {
+	fullBackupAt := ""
+	if c.FullBackupAt != nil {
+		fullBackupAt = c.FullBackupAt.Format(time.RFC3339)
+	}
+	return []Field{
+		{"category", c.Category},
+		{"filter", c.Filter},
+		{"username", c.Username},
+		{"full_backup_at", fullBackupAt},
+	}
+}
+
+var _ Described = (*List)(nil)
 
 type List struct {
 	Entries     []*Entry `json:"entries"`
@@ -77,3 +135,10 @@ func (l *List) CSV() []*Entry {
 func (l *List) JSON() interface{} {
 	return l
 }
+
+func (l *List) Metadata() []Field {
+	return []Field{
+		{"list_title", l.Title},
+		{"list_description", l.Description},
+	}
+}
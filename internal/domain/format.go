@@ -8,3 +8,17 @@ type Formatter interface {
 	// Ext returns the file extension
 	Ext() string
 }
+
+// Parser is implemented by the formatters whose output can be read back
+type Parser interface {
+	// Parse reads formatted data back into data
+	Parse(data Serializable, reader io.Reader) error
+}
+
+// Selective is implemented by the formatters that can only format some of
+// the data, like the films for Letterboxd. The data they don't support is
+// not saved.
+type Selective interface {
+	// Supports tells whether data can be formatted
+	Supports(data Serializable) bool
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "strconv"
+
+// ListInfo describes a list saved by a backup of all the lists of a user
+type ListInfo struct {
+	Title   string `json:"title"`
+	Slug    string `json:"slug"`
+	Entries int    `json:"entries"`
+	URL     string `json:"url"`
+}
+
+var _ Table = (*ListIndex)(nil)
+
+// ListIndex is the index of the lists of a user
+type ListIndex struct {
+	Lists    []*ListInfo `json:"lists"`
+	Username string      `json:"username"`
+}
+
+func NewListIndex(lists []*ListInfo, Username string) *ListIndex {
+	return &ListIndex{
+		Lists:    lists,
+		Username: Username,
+	}
+}
+
+func (i *ListIndex) Slug() string {
+	return "index"
+}
+
+// CSV returns nil: the index is not made of entries, see Records
+func (i *ListIndex) CSV() []*Entry {
+	return nil
+}
+
+func (i *ListIndex) JSON() interface{} {
+	return i
+}
+
+func (i *ListIndex) Header() []string {
+	return []string{"title", "slug", "entries", "url"}
+}
+
+func (i *ListIndex) Records() [][]string {
+	records := make([][]string, 0, len(i.Lists))
+	for _, list := range i.Lists {
+		records = append(records, []string{list.Title, list.Slug, strconv.Itoa(list.Entries), list.URL})
+	}
+	return records
+}
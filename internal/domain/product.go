@@ -0,0 +1,13 @@
+package domain
+
+// Product holds the details shown on the page of a product
+type Product struct {
+	ID  string `json:"id"`
+	URL string `json:"url,omitempty"`
+	// Runtime is in minutes
+	Runtime       int      `json:"runtime,omitempty"`
+	Countries     []string `json:"countries,omitempty"`
+	Synopsis      string   `json:"synopsis,omitempty"`
+	CoverURL      string   `json:"cover_url,omitempty"`
+	AverageRating float64  `json:"average_rating,omitempty"`
+}
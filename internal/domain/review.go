@@ -0,0 +1,63 @@
+package domain
+
+import "strconv"
+
+// Review is a review written by a user. It is linked to the entries of the
+// user's collections by its ProductID.
+type Review struct {
+	ID        string `json:"id"`
+	ProductID string `json:"product_id"`
+	Title     string `json:"title"`
+	HTML      string `json:"html"`
+	Text      string `json:"text"`
+	Date      string `json:"date,omitempty"`
+	Likes     int    `json:"likes"`
+	URL       string `json:"url"`
+}
+
+var _ Table = (*Reviews)(nil)
+
+type Reviews struct {
+	Reviews  []*Review `json:"reviews"`
+	Username string    `json:"username"`
+}
+
+func NewReviews(reviews []*Review, Username string) *Reviews {
+	return &Reviews{
+		Reviews:  reviews,
+		Username: Username,
+	}
+}
+
+func (r *Reviews) Slug() string {
+	return "reviews"
+}
+
+// CSV returns nil: the reviews are not entries, see Records
+func (r *Reviews) CSV() []*Entry {
+	return nil
+}
+
+func (r *Reviews) JSON() interface{} {
+	return r
+}
+
+func (r *Reviews) Header() []string {
+	return []string{"id", "product_id", "title", "text", "date", "likes", "url"}
+}
+
+func (r *Reviews) Records() [][]string {
+	records := make([][]string, 0, len(r.Reviews))
+	for _, review := range r.Reviews {
+		records = append(records, []string{
+			review.ID,
+			review.ProductID,
+			review.Title,
+			review.Text,
+			review.Date,
+			strconv.Itoa(review.Likes),
+			review.URL,
+		})
+	}
+	return records
+}
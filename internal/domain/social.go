@@ -0,0 +1,56 @@
+package domain
+
+// Person is a SensCritique user
+type Person struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	URL         string `json:"url"`
+}
+
+var _ Table = (*SocialGraph)(nil)
+
+// SocialGraph holds the users a user follows, its scouts ("éclaireurs"),
+// and its followers
+type SocialGraph struct {
+	Scouts    []*Person `json:"scouts"`
+	Followers []*Person `json:"followers"`
+	Username  string    `json:"username"`
+}
+
+func NewSocialGraph(scouts []*Person, followers []*Person, Username string) *SocialGraph {
+	return &SocialGraph{
+		Scouts:    scouts,
+		Followers: followers,
+		Username:  Username,
+	}
+}
+
+func (g *SocialGraph) Slug() string {
+	return "social"
+}
+
+// CSV returns nil: the social graph is not made of entries, see Records
+func (g *SocialGraph) CSV() []*Entry {
+	return nil
+}
+
+func (g *SocialGraph) JSON() interface{} {
+	return g
+}
+
+func (g *SocialGraph) Header() []string {
+	return []string{"relation", "username", "display_name", "url"}
+}
+
+// Records returns a row per scout then per follower, with the relation set
+// to scout or follower
+func (g *SocialGraph) Records() [][]string {
+	records := make([][]string, 0, len(g.Scouts)+len(g.Followers))
+	for _, person := range g.Scouts {
+		records = append(records, []string{"scout", person.Username, person.DisplayName, person.URL})
+	}
+	for _, person := range g.Followers {
+		records = append(records, []string{"follower", person.Username, person.DisplayName, person.URL})
+	}
+	return records
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrFullBackupNeeded is returned by IncrementalSource.CollectionSince when
+// the previous backup can't be updated
+var ErrFullBackupNeeded = errors.New("a full backup is needed")
+
+// Source fetches the data to backup from SensCritique
+type Source interface {
+	// ValidateUser returns an error if the user does not exist or if its
+	// profile is not public
+	ValidateUser(ctx context.Context, username string) error
+
+	// Collection fetches a user's collection for the given category and filter
+	Collection(ctx context.Context, username string, category string, filter string) (*Collection, error)
+
+	// List fetches a list
+	List(ctx context.Context, url string) (*List, error)
+
+	// Journal fetches a user's journal, restricted to the given categories,
+	// or for all of them if categories is empty. Only the ID and the
+	// DoneDate of the returned entries are set, or the ID of the series and
+	// the watched Episodes. There is an entry per event of the journal, so
+	// a product done several times is returned several times.
+	Journal(ctx context.Context, username string, categories []string) ([]*Entry, error)
+}
+
+// IncrementalSource is a Source able to update a previous backup of a
+// collection without fetching all its pages again
+type IncrementalSource interface {
+	Source
+
+	// CollectionSince fetches the newest entries of a collection until it
+	// reaches the ones of previous that haven't changed, and merges them
+	// into previous. It can't detect the deleted entries.
+	CollectionSince(ctx context.Context, username string, category string, filter string, previous *Collection) (*Collection, error)
+}
+
+// ReviewSource is a Source able to fetch the reviews written by a user
+type ReviewSource interface {
+	Source
+
+	// Reviews fetches all the reviews written by a user
+	Reviews(ctx context.Context, username string) (*Reviews, error)
+}
+
+// ListsSource is a Source able to enumerate the lists of a user
+type ListsSource interface {
+	Source
+
+	// Lists returns the URLs of the lists of a user
+	Lists(ctx context.Context, username string) ([]string, error)
+}
+
+// SocialSource is a Source able to fetch the social graph of a user
+type SocialSource interface {
+	Source
+
+	// SocialGraph fetches the scouts and the followers of a user
+	SocialGraph(ctx context.Context, username string) (*SocialGraph, error)
+}
+
+// ProductSource is a Source able to fetch the details of products
+type ProductSource interface {
+	Source
+
+	// Products fetches the details of the given products. When some of them
+	// fail, it returns the others along with the error.
+	Products(ctx context.Context, ids []string) ([]*Product, error)
+}
+
+// AssetSource is a Source able to download assets, like the posters
+type AssetSource interface {
+	Source
+
+	// Asset downloads the asset at url. The caller must close it.
+	Asset(ctx context.Context, url string) (io.ReadCloser, error)
+}
@@ -0,0 +1,199 @@
+package format
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+var _ domain.Formatter = (*Goodreads)(nil)
+var _ domain.Selective = (*Goodreads)(nil)
+var _ domain.Formatter = (*StoryGraph)(nil)
+var _ domain.Selective = (*StoryGraph)(nil)
+
+// The shelves of Goodreads, also the read statuses and tags of StoryGraph
+const (
+	shelfRead      = "read"
+	shelfToRead    = "to-read"
+	shelfReading   = "currently-reading"
+	shelfFavorites = "favorites"
+)
+
+// goodreadsHeader are the columns of the import format of Goodreads, see
+// https://www.goodreads.com/review/import
+var goodreadsHeader = []string{"Title", "Author", "Additional Authors", "My Rating", "Year Published", "Date Read", "Bookshelves", "Exclusive Shelf", "My Review", "Read Count"}
+
+// storyGraphHeader are the columns of the import format of StoryGraph
+var storyGraphHeader = []string{"Title", "Authors", "Read Status", "Last Date Read", "Dates Read", "Read Count", "Star Rating", "Review", "Tags"}
+
+// Goodreads formats the books and comics collections in the import format
+// of Goodreads
+type Goodreads struct{}
+
+func NewGoodreads() *Goodreads {
+	return &Goodreads{}
+}
+
+func (f *Goodreads) Ext() string {
+	return ".csv"
+}
+
+// Supports returns true for the books and comics collections
+func (f *Goodreads) Supports(data domain.Serializable) bool {
+	return isBooks(data)
+}
+
+func (f *Goodreads) Format(data domain.Serializable, writer io.Writer) error {
+	if !f.Supports(data) {
+		return fmt.Errorf("%s can't be formatted for Goodreads", data.Slug())
+	}
+
+	records := [][]string{goodreadsHeader}
+	for _, entry := range data.CSV() {
+		author, additionalAuthors := "", ""
+		if len(entry.Authors) > 0 {
+			author = entry.Authors[0]
+			additionalAuthors = strings.Join(entry.Authors[1:], ", ")
+		}
+
+		rating := ""
+		if entry.Rating != 0 {
+			// Goodreads only has whole stars
+			rating = strconv.Itoa((entry.Rating + 1) / 2)
+		}
+
+		dates := readDates(entry)
+		lastDate := ""
+		if len(dates) > 0 {
+			lastDate = dates[len(dates)-1]
+		}
+
+		shelf := bookShelf(entry)
+		shelves := shelf
+		if entry.Favorite {
+			shelves += ", " + shelfFavorites
+		}
+
+		records = append(records, []string{
+			entry.Title,
+			author,
+			additionalAuthors,
+			rating,
+			formatInt(entry.Year),
+			lastDate,
+			shelves,
+			shelf,
+			entry.Comment,
+			readCount(entry),
+		})
+	}
+
+	return csv.NewWriter(writer).WriteAll(records)
+}
+
+// StoryGraph formats the books and comics collections in the import format
+// of StoryGraph
+type StoryGraph struct{}
+
+func NewStoryGraph() *StoryGraph {
+	return &StoryGraph{}
+}
+
+func (f *StoryGraph) Ext() string {
+	return ".csv"
+}
+
+// Supports returns true for the books and comics collections
+func (f *StoryGraph) Supports(data domain.Serializable) bool {
+	return isBooks(data)
+}
+
+func (f *StoryGraph) Format(data domain.Serializable, writer io.Writer) error {
+	if !f.Supports(data) {
+		return fmt.Errorf("%s can't be formatted for StoryGraph", data.Slug())
+	}
+
+	records := [][]string{storyGraphHeader}
+	for _, entry := range data.CSV() {
+		rating := ""
+		if entry.Rating != 0 {
+			// StoryGraph has half stars
+			rating = strconv.FormatFloat(float64(entry.Rating)/2, 'f', -1, 64)
+		}
+
+		dates := readDates(entry)
+		lastDate := ""
+		if len(dates) > 0 {
+			lastDate = dates[len(dates)-1]
+		}
+
+		tags := ""
+		if entry.Favorite {
+			tags = shelfFavorites
+		}
+
+		records = append(records, []string{
+			entry.Title,
+			strings.Join(entry.Authors, ", "),
+			bookShelf(entry),
+			lastDate,
+			strings.Join(dates, ", "),
+			readCount(entry),
+			rating,
+			entry.Comment,
+			tags,
+		})
+	}
+
+	return csv.NewWriter(writer).WriteAll(records)
+}
+
+// isBooks tells whether data is a books or comics collection
+func isBooks(data domain.Serializable) bool {
+	collection, ok := data.(*domain.Collection)
+	return ok && (collection.Category == "livres" || collection.Category == "bd")
+}
+
+// bookShelf returns the shelf of an entry, from its state
+func bookShelf(entry *domain.Entry) string {
+	switch entry.State {
+	case domain.StateWish:
+		return shelfToRead
+	case domain.StateInProgress:
+		return shelfReading
+	}
+	return shelfRead
+}
+
+// readDates returns the dates an entry was read, from the oldest to the most
+// recent. The dates without a day are left out, as they can't be imported.
+func readDates(entry *domain.Entry) []string {
+	dates := entry.DoneDates
+	if len(dates) == 0 && entry.DoneDate != nil {
+		dates = []domain.PartialDate{*entry.DoneDate}
+	}
+
+	formatted := make([]string, 0, len(dates))
+	for _, date := range dates {
+		if date.Precision == domain.PrecisionDay {
+			formatted = append(formatted, fmt.Sprintf("%04d/%02d/%02d", date.Year, date.Month, date.Day))
+		}
+	}
+	return formatted
+}
+
+// readCount returns the number of times an entry was read, at least 1 if it
+// was read
+func readCount(entry *domain.Entry) string {
+	if entry.State != domain.StateDone {
+		return "0"
+	}
+	if len(entry.DoneDates) > 1 {
+		return strconv.Itoa(len(entry.DoneDates))
+	}
+	return "1"
+}
@@ -0,0 +1,81 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func testBooks() []*domain.Entry {
+	dates := []domain.PartialDate{}
+	for _, raw := range []string{"2014-00-00", "2016-07-09", "2020-10-25"} {
+		date, _ := domain.ParsePartialDate(raw)
+		dates = append(dates, date)
+	}
+
+	return []*domain.Entry{
+		{
+			ID:        "1",
+			Title:     "Dune",
+			Year:      1965,
+			Authors:   []string{"Frank Herbert"},
+			Rating:    7,
+			DoneDate:  &dates[2],
+			DoneDates: dates,
+			Comment:   "Relu avec plaisir",
+			Favorite:  true,
+			State:     domain.StateDone,
+		},
+		{ID: "2", Title: "Blacksad", Authors: []string{"Juan Díaz Canales", "Juanjo Guarnido"}, State: domain.StateWish},
+		{ID: "3", Title: "Les Misérables", Authors: []string{"Victor Hugo"}, State: domain.StateInProgress},
+	}
+}
+
+func TestGoodreads(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewGoodreads().Format(domain.NewCollection(testBooks(), "livres", "done", "mlcdf"), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"Title,Author,Additional Authors,My Rating,Year Published,Date Read,Bookshelves,Exclusive Shelf,My Review,Read Count",
+		`Dune,Frank Herbert,,4,1965,2020/10/25,"read, favorites",read,Relu avec plaisir,3`,
+		"Blacksad,Juan Díaz Canales,Juanjo Guarnido,,,,to-read,to-read,,0",
+		"Les Misérables,Victor Hugo,,,,,currently-reading,currently-reading,,0",
+		"",
+	}, "\n")
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestStoryGraph(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewStoryGraph().Format(domain.NewCollection(testBooks(), "bd", "done", "mlcdf"), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"Title,Authors,Read Status,Last Date Read,Dates Read,Read Count,Star Rating,Review,Tags",
+		`Dune,Frank Herbert,read,2020/10/25,"2016/07/09, 2020/10/25",3,3.5,Relu avec plaisir,favorites`,
+		`Blacksad,"Juan Díaz Canales, Juanjo Guarnido",to-read,,,0,,,`,
+		"Les Misérables,Victor Hugo,currently-reading,,,0,,,",
+		"",
+	}, "\n")
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestBooksSupports(t *testing.T) {
+	for _, f := range []domain.Selective{NewGoodreads(), NewStoryGraph()} {
+		if !f.Supports(domain.NewCollection(nil, "bd", "wish", "mlcdf")) {
+			t.Errorf("%T: expected bd to be supported", f)
+		}
+		if f.Supports(domain.NewCollection(nil, "films", "done", "mlcdf")) || f.Supports(domain.NewList(nil, "Livres", "")) {
+			t.Errorf("%T: expected only the books and comics collections to be supported", f)
+		}
+	}
+}
@@ -1,38 +1,503 @@
 package format
 
 import (
+	"bufio"
 	"encoding/csv"
+	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.mlcdf.fr/sc-backup/internal/domain"
 )
 
 var _ domain.Formatter = (*CSV)(nil)
+var _ domain.Parser = (*CSV)(nil)
 
-type CSV struct{}
+// CSVOptions configures the CSV format. The zero value writes every column,
+// separated by commas, with the values of the list fields separated by
+// semicolons.
+type CSVOptions struct {
+	// Delimiter separates the columns
+	Delimiter rune
+	// ListSeparator separates the values of the list fields, like the authors
+	ListSeparator string
+	// BOM starts the files with a UTF-8 byte order mark, for Excel to detect
+	// the encoding
+	BOM bool
+	// Columns are the names of the columns to write, in order. The columns
+	// of the tables, like the reviews, can't be selected.
+	Columns []string
+}
+
+type CSV struct {
+	opts CSVOptions
+}
+
+// NewCSV returns a CSV format, or an error if the delimiter or a column is
+// invalid
+func NewCSV(opts CSVOptions) (*CSV, error) {
+	switch opts.Delimiter {
+	case '"', '\r', '\n', utf8.RuneError:
+		return nil, fmt.Errorf("invalid delimiter %q", opts.Delimiter)
+	}
+
+	known := map[string]bool{}
+	for _, name := range Columns() {
+		known[name] = true
+	}
+	for _, name := range opts.Columns {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %s, it should be one of %s", name, strings.Join(Columns(), ", "))
+		}
+	}
+	return &CSV{opts}, nil
+}
+
+// Columns returns the names of the columns of the collections and lists
+func Columns() []string {
+	names := []string{}
+	for _, described := range []domain.Described{&domain.Collection{}, &domain.List{}} {
+		for _, field := range described.Metadata() {
+			names = append(names, field.Name)
+		}
+	}
+	for _, column := range entryColumns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// ParseDelimiter parses a delimiter made of a single character. "\t" and
+// "tab" stand for a tab.
+func ParseDelimiter(s string) (rune, error) {
+	if s == `\t` || s == "tab" {
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, fmt.Errorf("invalid delimiter %q: it should be a single character", s)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
 
 func (f *CSV) Ext() string {
 	return ".csv"
 }
 
 func (f *CSV) Format(data domain.Serializable, writer io.Writer) error {
-	mapMapString := make([][]string, 0, len(data.CSV()))
+	if f.opts.BOM {
+		if _, err := io.WriteString(writer, "\ufeff"); err != nil {
+			return err
+		}
+	}
 
 	w := csv.NewWriter(writer)
+	if f.opts.Delimiter != 0 {
+		w.Comma = f.opts.Delimiter
+	}
+
+	if table, ok := data.(domain.Table); ok {
+		if err := w.Write(table.Header()); err != nil {
+			return err
+		}
+		return w.WriteAll(table.Records())
+	}
+
+	separator := f.opts.ListSeparator
+	if separator == "" {
+		separator = ";"
+	}
+
+	// the metadata is the same for every entry
+	metadata := map[string]string{}
+	header := []string{}
+	if described, ok := data.(domain.Described); ok {
+		for _, field := range described.Metadata() {
+			metadata[field.Name] = field.Value
+			header = append(header, field.Name)
+		}
+	}
+	for _, column := range entryColumns {
+		header = append(header, column.name)
+	}
+	if len(f.opts.Columns) > 0 {
+		header = f.opts.Columns
+	}
+
+	records := make([][]string, 0, len(data.CSV())+1)
+	records = append(records, header)
 	for _, entry := range data.CSV() {
-		mapString := []string{
-			entry.ID,
-			entry.Title,
-			entry.OriginalTitle,
-			strconv.Itoa(entry.Year),
-			strings.Join(entry.Authors, ";"),
-			strconv.Itoa(entry.Rating),
+		record := make([]string, 0, len(header))
+		for _, name := range header {
+			if value, ok := metadata[name]; ok {
+				record = append(record, value)
+			} else if column := findColumn(name); column != nil {
+				record = append(record, column.value(entry, separator))
+			} else {
+				// the metadata of another kind of data, like the title of a
+				// list for a collection
+				record = append(record, "")
+			}
+		}
+		records = append(records, record)
+	}
+
+	return w.WriteAll(records)
+}
+
+// Parse reads a collection or a list written by Format back. The columns
+// missing from the file are left empty, so only a file with every column
+// holds the whole data. The values of the list fields, like the authors,
+// must not contain the list separator.
+func (f *CSV) Parse(data domain.Serializable, reader io.Reader) error {
+	// the byte order mark is skipped whether the options ask for it or not
+	buffered := bufio.NewReader(reader)
+	if r, _, err := buffered.ReadRune(); err == nil && r != '\ufeff' {
+		buffered.UnreadRune()
+	}
+
+	r := csv.NewReader(buffered)
+	if f.opts.Delimiter != 0 {
+		r.Comma = f.opts.Delimiter
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s: missing CSV header", data.Slug())
+	}
+
+	separator := f.opts.ListSeparator
+	if separator == "" {
+		separator = ";"
+	}
+
+	header := records[0]
+	metadata := map[string]string{}
+	entries := make([]*domain.Entry, 0, len(records)-1)
+	for i, record := range records[1:] {
+		entry := &domain.Entry{}
+		for j, name := range header {
+			column := findColumn(name)
+			if column == nil {
+				metadata[name] = record[j]
+				continue
+			}
+			if err := column.parse(entry, record[j], separator); err != nil {
+				return fmt.Errorf("%s: line %d: invalid %s %q: %s", data.Slug(), i+2, name, record[j], err)
+			}
+		}
+
+		// the columns of the done date, the episodes and the details
+		// belong to the entry, whatever their order
+		if date := entry.DoneDate; date != nil {
+			*date = domain.NewPartialDate(date.Year, date.Month, date.Day)
 		}
-		mapMapString = append(mapMapString, mapString)
+		for _, episode := range entry.Episodes {
+			episode.SeriesID = entry.ID
+		}
+		if entry.Details != nil {
+			entry.Details.ID = entry.ID
+		}
+		entries = append(entries, entry)
+	}
+
+	switch data := data.(type) {
+	case *domain.Collection:
+		data.Entries = entries
+		if len(entries) == 0 {
+			// the metadata is only written along with the entries
+			return nil
+		}
+		data.Category, data.Filter, data.Username = metadata["category"], metadata["filter"], metadata["username"]
+		data.FullBackupAt = nil
+		if value := metadata["full_backup_at"]; value != "" {
+			fullBackupAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid full_backup_at %q: %s", data.Slug(), value, err)
+			}
+			data.FullBackupAt = &fullBackupAt
+		}
+	case *domain.List:
+		data.Entries = entries
+		if len(entries) > 0 {
+			data.Title, data.Description = metadata["list_title"], metadata["list_description"]
+		}
+	default:
+		return fmt.Errorf("%s can't be read back from CSV", data.Slug())
+	}
+	return nil
+}
+
+// column is a column of the entries. parse sets the field formatted by
+// value.
+type column struct {
+	name  string
+	value func(entry *domain.Entry, separator string) string
+	parse func(entry *domain.Entry, value string, separator string) error
+}
+
+var entryColumns = []*column{
+	{"id",
+		func(e *domain.Entry, _ string) string { return e.ID },
+		func(e *domain.Entry, v string, _ string) error { e.ID = v; return nil }},
+	{"title",
+		func(e *domain.Entry, _ string) string { return e.Title },
+		func(e *domain.Entry, v string, _ string) error { e.Title = v; return nil }},
+	{"original_title",
+		func(e *domain.Entry, _ string) string { return e.OriginalTitle },
+		func(e *domain.Entry, v string, _ string) error { e.OriginalTitle = v; return nil }},
+	{"year",
+		func(e *domain.Entry, _ string) string { return formatInt(e.Year) },
+		func(e *domain.Entry, v string, _ string) (err error) { e.Year, err = parseInt(v); return }},
+	{"authors",
+		func(e *domain.Entry, sep string) string { return strings.Join(e.Authors, sep) },
+		func(e *domain.Entry, v string, sep string) error { e.Authors = splitList(v, sep); return nil }},
+	{"rating",
+		func(e *domain.Entry, _ string) string { return formatInt(e.Rating) },
+		func(e *domain.Entry, v string, _ string) (err error) { e.Rating, err = parseInt(v); return }},
+	{"done_year",
+		func(e *domain.Entry, _ string) string { return dateColumn(e.DoneDate, 0) },
+		func(e *domain.Entry, v string, _ string) error { return parseDateColumn(e, v, 0) }},
+	{"done_month",
+		func(e *domain.Entry, _ string) string { return dateColumn(e.DoneDate, 1) },
+		func(e *domain.Entry, v string, _ string) error { return parseDateColumn(e, v, 1) }},
+	{"done_day",
+		func(e *domain.Entry, _ string) string { return dateColumn(e.DoneDate, 2) },
+		func(e *domain.Entry, v string, _ string) error { return parseDateColumn(e, v, 2) }},
+	{"done_dates",
+		func(e *domain.Entry, sep string) string {
+			dates := make([]string, 0, len(e.DoneDates))
+			for _, date := range e.DoneDates {
+				dates = append(dates, date.String())
+			}
+			return strings.Join(dates, sep)
+		},
+		func(e *domain.Entry, v string, sep string) error {
+			for _, value := range splitList(v, sep) {
+				date, err := domain.ParsePartialDate(value)
+				if err != nil {
+					return err
+				}
+				e.DoneDates = append(e.DoneDates, date)
+			}
+			return nil
+		}},
+	{"comment",
+		func(e *domain.Entry, _ string) string { return e.Comment },
+		func(e *domain.Entry, v string, _ string) error { e.Comment = v; return nil }},
+	{"favorite",
+		func(e *domain.Entry, _ string) string { return strconv.FormatBool(e.Favorite) },
+		func(e *domain.Entry, v string, _ string) (err error) {
+			if v != "" {
+				e.Favorite, err = strconv.ParseBool(v)
+			}
+			return
+		}},
+	{"genres",
+		func(e *domain.Entry, sep string) string { return strings.Join(e.Genres, sep) },
+		func(e *domain.Entry, v string, sep string) error { e.Genres = splitList(v, sep); return nil }},
+	{"state",
+		func(e *domain.Entry, _ string) string { return e.State },
+		func(e *domain.Entry, v string, _ string) error { e.State = v; return nil }},
+	{"platforms",
+		func(e *domain.Entry, sep string) string { return strings.Join(e.Platforms, sep) },
+		func(e *domain.Entry, v string, sep string) error { e.Platforms = splitList(v, sep); return nil }},
+	{"episodes",
+		func(e *domain.Entry, sep string) string {
+			episodes := make([]string, 0, len(e.Episodes))
+			for _, episode := range e.Episodes {
+				episodes = append(episodes, formatEpisode(episode))
+			}
+			return strings.Join(episodes, sep)
+		},
+		func(e *domain.Entry, v string, sep string) error {
+			for _, value := range splitList(v, sep) {
+				episode, err := parseEpisode(value)
+				if err != nil {
+					return err
+				}
+				e.Episodes = append(e.Episodes, episode)
+			}
+			return nil
+		}},
+	{"url",
+		func(e *domain.Entry, _ string) string { return details(e).URL },
+		func(e *domain.Entry, v string, _ string) error {
+			if v != "" {
+				setDetails(e).URL = v
+			}
+			return nil
+		}},
+	{"runtime",
+		func(e *domain.Entry, _ string) string { return formatInt(details(e).Runtime) },
+		func(e *domain.Entry, v string, _ string) (err error) {
+			if v != "" {
+				setDetails(e).Runtime, err = parseInt(v)
+			}
+			return
+		}},
+	{"countries",
+		func(e *domain.Entry, sep string) string { return strings.Join(details(e).Countries, sep) },
+		func(e *domain.Entry, v string, sep string) error {
+			if v != "" {
+				setDetails(e).Countries = splitList(v, sep)
+			}
+			return nil
+		}},
+	{"synopsis",
+		func(e *domain.Entry, _ string) string { return details(e).Synopsis },
+		func(e *domain.Entry, v string, _ string) error {
+			if v != "" {
+				setDetails(e).Synopsis = v
+			}
+			return nil
+		}},
+	{"cover_url",
+		func(e *domain.Entry, _ string) string { return details(e).CoverURL },
+		func(e *domain.Entry, v string, _ string) error {
+			if v != "" {
+				setDetails(e).CoverURL = v
+			}
+			return nil
+		}},
+	{"average_rating",
+		func(e *domain.Entry, _ string) string {
+			if rating := details(e).AverageRating; rating != 0 {
+				return strconv.FormatFloat(rating, 'f', -1, 64)
+			}
+			return ""
+		},
+		func(e *domain.Entry, v string, _ string) (err error) {
+			if v != "" {
+				setDetails(e).AverageRating, err = strconv.ParseFloat(v, 64)
+			}
+			return
+		}},
+	{"poster",
+		func(e *domain.Entry, _ string) string { return e.Poster },
+		func(e *domain.Entry, v string, _ string) error { e.Poster = v; return nil }},
+	{"poster_path",
+		func(e *domain.Entry, _ string) string { return e.PosterPath },
+		func(e *domain.Entry, v string, _ string) error { e.PosterPath = v; return nil }},
+}
+
+func findColumn(name string) *column {
+	for _, column := range entryColumns {
+		if column.name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+// formatInt formats n, or returns "" if it is 0, for an unknown value
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// parseInt parses a column formatted by formatInt
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// splitList splits the values of a list field, or returns nil if it is empty
+func splitList(s string, separator string) []string {
+	if s == "" {
+		return nil
 	}
+	return strings.Split(s, separator)
+}
+
+// dateColumn returns the year, month or day of date
+func dateColumn(date *domain.PartialDate, i int) string {
+	if date == nil {
+		return ""
+	}
+	return date.Columns()[i]
+}
+
+// parseDateColumn sets the year, month or day of the done date of entry. The
+// precision of the date is set once all its columns are parsed.
+func parseDateColumn(entry *domain.Entry, s string, i int) error {
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if entry.DoneDate == nil {
+		entry.DoneDate = &domain.PartialDate{}
+	}
+	switch i {
+	case 0:
+		entry.DoneDate.Year = n
+	case 1:
+		entry.DoneDate.Month = n
+	case 2:
+		entry.DoneDate.Day = n
+	}
+	return nil
+}
+
+// formatEpisode formats an episode like 1234:S02E13, with its ID and its
+// number, followed by the date it was watched if any, like
+// 1234:S02E13@2020-12-04. The series of the episode is the entry.
+func formatEpisode(episode *domain.Episode) string {
+	s := fmt.Sprintf("%s:S%02dE%02d", episode.ID, episode.Season, episode.Number)
+	if episode.WatchedDate != nil {
+		s += "@" + episode.WatchedDate.String()
+	}
+	return s
+}
+
+var episodeFormat = regexp.MustCompile(`^(.*):S(\d+)E(\d+)(?:@(.+))?$`)
+
+// parseEpisode parses an episode formatted by formatEpisode
+func parseEpisode(s string) (*domain.Episode, error) {
+	matches := episodeFormat.FindStringSubmatch(s)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid episode %q", s)
+	}
+
+	episode := &domain.Episode{ID: matches[1]}
+	episode.Season, _ = strconv.Atoi(matches[2])
+	episode.Number, _ = strconv.Atoi(matches[3])
+	if matches[4] != "" {
+		date, err := domain.ParsePartialDate(matches[4])
+		if err != nil {
+			return nil, err
+		}
+		episode.WatchedDate = &date
+	}
+	return episode, nil
+}
+
+// details returns the details of an entry, or empty ones if it wasn't
+// enriched
+func details(entry *domain.Entry) *domain.Product {
+	if entry.Details == nil {
+		return &domain.Product{}
+	}
+	return entry.Details
+}
 
-	err := w.WriteAll(mapMapString)
-	return err
+// setDetails returns the details of an entry, setting them if it has none
+func setDetails(entry *domain.Entry) *domain.Product {
+	if entry.Details == nil {
+		entry.Details = &domain.Product{}
+	}
+	return entry.Details
 }
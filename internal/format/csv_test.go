@@ -0,0 +1,200 @@
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func testCollection() *domain.Collection {
+	date, _ := domain.ParsePartialDate("2020-12-00")
+	return domain.NewCollection([]*domain.Entry{
+		{
+			ID:       "491576",
+			Title:    "La Cabane dans les bois",
+			Year:     2012,
+			Authors:  []string{"Drew Goddard"},
+			Rating:   7,
+			DoneDate: &date,
+			Comment:  "Vu en 3D, avec des amis",
+			Favorite: true,
+			Genres:   []string{"Horreur", "Comédie"},
+		},
+		{ID: "388729", Title: "Munich"},
+	}, "films", "done", "mlcdf")
+}
+
+func TestCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := (&CSV{}).Format(testCollection(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(records); l != 3 {
+		t.Fatalf("expected a header and 2 records, got %d rows", l)
+	}
+
+	row := map[string]string{}
+	for i, name := range records[0] {
+		row[name] = records[1][i]
+	}
+	expected := map[string]string{
+		"category":   "films",
+		"username":   "mlcdf",
+		"id":         "491576",
+		"year":       "2012",
+		"rating":     "7",
+		"done_year":  "2020",
+		"done_month": "12",
+		"done_day":   "",
+		"comment":    "Vu en 3D, avec des amis",
+		"favorite":   "true",
+		"genres":     "Horreur;Comédie",
+		"state":      "done",
+	}
+	for name, value := range expected {
+		if row[name] != value {
+			t.Errorf("%s: expected %q, got %q", name, value, row[name])
+		}
+	}
+
+	if len(records[0]) != len(records[2]) || records[2][len(records[2])-1] != "" {
+		t.Errorf("unexpected record %v", records[2])
+	}
+}
+
+func TestCSVOptions(t *testing.T) {
+	f, err := NewCSV(CSVOptions{
+		Delimiter:     '\t',
+		ListSeparator: "|",
+		BOM:           true,
+		Columns:       []string{"title", "genres", "list_title"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Format(testCollection(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\ufefftitle\tgenres\tlist_title\nLa Cabane dans les bois\tHorreur|Comédie\t\nMunich\t\t\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+
+	if _, err := NewCSV(CSVOptions{Columns: []string{"title", "unknown"}}); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("expected an error for the unknown column, got %v", err)
+	}
+	if _, err := NewCSV(CSVOptions{Delimiter: '"'}); err == nil {
+		t.Errorf("expected an error for the invalid delimiter")
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	date := func(s string) *domain.PartialDate {
+		d, err := domain.ParsePartialDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+
+	collection := domain.NewCollection([]*domain.Entry{
+		{
+			ID:        "491576",
+			Title:     "La Cabane dans les bois",
+			Authors:   []string{"Drew Goddard"},
+			DoneDate:  date("2020"),
+			DoneDates: []domain.PartialDate{*date("2012-05"), *date("2020")},
+			Comment:   "Vu en 3D,\n\"avec des amis\"",
+			Details: &domain.Product{
+				ID:            "491576",
+				URL:           "https://www.senscritique.com/film/la_cabane_dans_les_bois/491576",
+				Runtime:       95,
+				Countries:     []string{"États-Unis", "Canada"},
+				Synopsis:      "Cinq amis partent passer le week-end dans une cabane isolée.",
+				AverageRating: 6.4,
+			},
+			Poster:     "https://media.senscritique.com/491576.jpg",
+			PosterPath: "posters/491576.jpg",
+		},
+		{
+			ID:       "8853524",
+			Title:    "The Fall",
+			Favorite: true,
+			Genres:   []string{"Policier", "Drame"},
+			Episodes: []*domain.Episode{
+				{ID: "1234", SeriesID: "8853524", Season: 2, Number: 13, WatchedDate: date("2020-12-04")},
+				{ID: "1235", SeriesID: "8853524"},
+			},
+		},
+	}, "series", "done", "mlcdf")
+	fullBackupAt := time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC)
+	collection.FullBackupAt = &fullBackupAt
+
+	list := domain.NewList([]*domain.Entry{{ID: "388729", Title: "Munich", Year: 2005, Rating: 8}}, "Spielberg", "Ses meilleurs films")
+
+	for _, opts := range []CSVOptions{{}, {Delimiter: '\t', ListSeparator: "|", BOM: true}} {
+		f, err := NewCSV(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, data := range []domain.Serializable{collection, list} {
+			var b bytes.Buffer
+			if err := f.Format(data, &b); err != nil {
+				t.Fatal(err)
+			}
+
+			var parsed domain.Serializable = &domain.Collection{}
+			if _, ok := data.(*domain.List); ok {
+				parsed = &domain.List{}
+			}
+			if err := f.Parse(parsed, &b); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(parsed, data) {
+				t.Errorf("%+v: expected %+v, got %+v", opts, data, parsed)
+			}
+		}
+	}
+}
+
+func TestCSVParseErrors(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"id,year\n1,unknown\n",
+		"id,episodes\n1,S02E13\n",
+		"id,done_dates\n1,2020-13\n",
+	} {
+		if err := (&CSV{}).Parse(&domain.Collection{}, strings.NewReader(content)); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+
+	if err := (&CSV{}).Parse(&domain.Reviews{}, strings.NewReader("id\n1\n")); err == nil {
+		t.Errorf("expected an error for data without entries")
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for raw, expected := range map[string]rune{";": ';', "tab": '\t', `\t`: '\t', "|": '|'} {
+		if r, err := ParseDelimiter(raw); err != nil || r != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", raw, expected, r, err)
+		}
+	}
+	if _, err := ParseDelimiter(";;"); err == nil {
+		t.Errorf("expected an error for a delimiter of 2 characters")
+	}
+}
@@ -8,6 +8,7 @@ import (
 )
 
 var _ domain.Formatter = (*JSON)(nil)
+var _ domain.Parser = (*JSON)(nil)
 
 type JSON struct {
 	pretty bool
@@ -37,3 +38,7 @@ func (f *JSON) Format(data domain.Serializable, writer io.Writer) error {
 	_, err = writer.Write(formatted)
 	return err
 }
+
+func (f *JSON) Parse(data domain.Serializable, reader io.Reader) error {
+	return json.NewDecoder(reader).Decode(data.JSON())
+}
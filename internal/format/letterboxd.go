@@ -0,0 +1,142 @@
+package format
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+var _ domain.Formatter = (*Letterboxd)(nil)
+var _ domain.Selective = (*Letterboxd)(nil)
+
+// letterboxdHeader are the columns of the import format of Letterboxd, see
+// https://letterboxd.com/about/importing-data/
+var letterboxdHeader = []string{"Title", "Year", "Directors", "Rating10", "WatchedDate", "Rewatch", "Review"}
+
+// Report is a film that Letterboxd may fail to import as is, like a film
+// without a year it can't match
+type Report struct {
+	Slug   string
+	ID     string
+	Title  string
+	Reason string
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%s: %s (%s): %s", r.Slug, r.Title, r.ID, r.Reason)
+}
+
+// Letterboxd formats the films in the import format of Letterboxd: the done
+// films as a diary, with a row per watch, and the wished films as a
+// watchlist. The other collections are not supported.
+type Letterboxd struct {
+	mu      sync.Mutex
+	reports []*Report
+}
+
+func NewLetterboxd() *Letterboxd {
+	return &Letterboxd{}
+}
+
+func (f *Letterboxd) Ext() string {
+	return ".csv"
+}
+
+// Supports returns true for the film collections and the lists
+func (f *Letterboxd) Supports(data domain.Serializable) bool {
+	switch data := data.(type) {
+	case *domain.Collection:
+		return data.Category == "films"
+	case *domain.List:
+		return true
+	}
+	return false
+}
+
+// Reports returns the films formatted so far that Letterboxd may fail to
+// import as is
+func (f *Letterboxd) Reports() []*Report {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*Report(nil), f.reports...)
+}
+
+func (f *Letterboxd) Format(data domain.Serializable, writer io.Writer) error {
+	if !f.Supports(data) {
+		return fmt.Errorf("%s can't be formatted for Letterboxd", data.Slug())
+	}
+
+	records := [][]string{letterboxdHeader}
+	for _, entry := range data.CSV() {
+		records = append(records, f.records(data.Slug(), entry)...)
+	}
+
+	w := csv.NewWriter(writer)
+	return w.WriteAll(records)
+}
+
+// records returns a row per watch of entry, or a single row if it was never
+// watched
+func (f *Letterboxd) records(slug string, entry *domain.Entry) [][]string {
+	title := entry.Title
+	if entry.OriginalTitle != "" {
+		// Letterboxd is more likely to know the original title
+		title = entry.OriginalTitle
+	}
+
+	year := ""
+	if entry.Year != 0 {
+		year = strconv.Itoa(entry.Year)
+	}
+
+	switch {
+	case title == "":
+		f.report(slug, entry, "no title")
+	case year == "":
+		f.report(slug, entry, "no year")
+	}
+
+	rating := ""
+	if entry.Rating != 0 {
+		// SensCritique rates out of 10, like the half-stars of Letterboxd
+		rating = strconv.Itoa(entry.Rating)
+	}
+
+	dates := entry.DoneDates
+	if len(dates) == 0 && entry.DoneDate != nil {
+		dates = []domain.PartialDate{*entry.DoneDate}
+	}
+	if entry.State != domain.StateDone || len(dates) == 0 {
+		return [][]string{{title, year, strings.Join(entry.Authors, ", "), rating, "", "", entry.Comment}}
+	}
+
+	records := make([][]string, 0, len(dates))
+	for i, date := range dates {
+		watched := ""
+		if date.Precision == domain.PrecisionDay {
+			watched = date.String()
+		} else {
+			f.report(slug, entry, fmt.Sprintf("the watched date %s is not precise enough", date))
+		}
+
+		review := ""
+		if i == len(dates)-1 {
+			// the comment is about the last watch
+			review = entry.Comment
+		}
+
+		records = append(records, []string{title, year, strings.Join(entry.Authors, ", "), rating, watched, strconv.FormatBool(i > 0), review})
+	}
+	return records
+}
+
+func (f *Letterboxd) report(slug string, entry *domain.Entry, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reports = append(f.reports, &Report{slug, entry.ID, entry.Title, reason})
+}
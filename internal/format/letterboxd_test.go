@@ -0,0 +1,80 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func TestLetterboxd(t *testing.T) {
+	dates := []domain.PartialDate{}
+	for _, raw := range []string{"2016-07-09", "2020", "2020-10-25"} {
+		date, _ := domain.ParsePartialDate(raw)
+		dates = append(dates, date)
+	}
+
+	done := domain.NewCollection([]*domain.Entry{
+		{
+			ID:        "388729",
+			Title:     "Munich",
+			Year:      2005,
+			Authors:   []string{"Steven Spielberg"},
+			Rating:    8,
+			DoneDate:  &dates[2],
+			DoneDates: dates,
+			Comment:   "Haletant",
+		},
+		{ID: "491576", Title: "La Cabane dans les bois", OriginalTitle: "The Cabin in the Woods", Year: 2012},
+		{ID: "11026448", Title: "Quelques minutes après minuit"},
+	}, "films", domain.StateDone, "mlcdf")
+
+	f := NewLetterboxd()
+	var b bytes.Buffer
+	if err := f.Format(done, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"Title,Year,Directors,Rating10,WatchedDate,Rewatch,Review",
+		"Munich,2005,Steven Spielberg,8,2016-07-09,false,",
+		"Munich,2005,Steven Spielberg,8,,true,",
+		"Munich,2005,Steven Spielberg,8,2020-10-25,true,Haletant",
+		"The Cabin in the Woods,2012,,,,,",
+		"Quelques minutes après minuit,,,,,,",
+		"",
+	}, "\n")
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	reports := f.Reports()
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %v", reports)
+	}
+	if !strings.Contains(reports[0].Reason, "2020") || reports[1].ID != "11026448" || reports[1].Reason != "no year" {
+		t.Errorf("unexpected reports %v", reports)
+	}
+
+	if f.Supports(domain.NewCollection(nil, "series", domain.StateDone, "mlcdf")) {
+		t.Errorf("expected the series not to be supported")
+	}
+	if err := f.Format(domain.NewDiary(nil, "mlcdf"), &b); err == nil {
+		t.Errorf("expected an error for the diary")
+	}
+}
+
+func TestLetterboxdWatchlist(t *testing.T) {
+	wish := domain.NewCollection([]*domain.Entry{
+		{ID: "38918801", Title: "Tenet", Year: 2020, Authors: []string{"Christopher Nolan"}},
+	}, "films", domain.StateWish, "mlcdf")
+
+	var b bytes.Buffer
+	if err := NewLetterboxd().Format(wish, &b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(b.String(), "\nTenet,2020,Christopher Nolan,,,,\n") {
+		t.Errorf("unexpected watchlist %s", b.String())
+	}
+}
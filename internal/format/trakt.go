@@ -0,0 +1,193 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+var _ domain.Formatter = (*Trakt)(nil)
+var _ domain.Selective = (*Trakt)(nil)
+
+// traktReleased is the watched_at of Trakt for the watches without a known
+// day: Trakt sets the current time when it's missing
+const traktReleased = "released"
+
+// traktItem is a movie or show of the sync API of Trakt, see
+// https://trakt.docs.apiary.io/#reference/sync. Trakt matches them by title
+// and year.
+type traktItem struct {
+	Title     string         `json:"title"`
+	Year      int            `json:"year,omitempty"`
+	WatchedAt string         `json:"watched_at,omitempty"`
+	Rating    int            `json:"rating,omitempty"`
+	Seasons   []*traktSeason `json:"seasons,omitempty"`
+}
+
+type traktSeason struct {
+	Number   int             `json:"number"`
+	Episodes []*traktEpisode `json:"episodes"`
+}
+
+type traktEpisode struct {
+	Number    int    `json:"number"`
+	WatchedAt string `json:"watched_at"`
+}
+
+// traktItems is the body of a request of the sync API
+type traktItems struct {
+	Movies []*traktItem `json:"movies,omitempty"`
+	Shows  []*traktItem `json:"shows,omitempty"`
+}
+
+func (i *traktItems) add(category string, item *traktItem) {
+	if category == "films" {
+		i.Movies = append(i.Movies, item)
+	} else {
+		i.Shows = append(i.Shows, item)
+	}
+}
+
+func (i *traktItems) empty() bool {
+	return len(i.Movies) == 0 && len(i.Shows) == 0
+}
+
+// traktExport holds the bodies of the requests adding a collection to the
+// history, the ratings and the watchlist
+type traktExport struct {
+	History   *traktItems `json:"history,omitempty"`
+	Ratings   *traktItems `json:"ratings,omitempty"`
+	Watchlist *traktItems `json:"watchlist,omitempty"`
+}
+
+// Trakt formats the films and series collections as the bodies of the
+// requests of the sync API of Trakt: the history, with a watch per done
+// date or per watched episode, the ratings and the watchlist
+type Trakt struct {
+	pretty bool
+}
+
+func NewTrakt(pretty bool) *Trakt {
+	return &Trakt{pretty}
+}
+
+func (f *Trakt) Ext() string {
+	return ".json"
+}
+
+// Supports returns true for the films and series collections
+func (f *Trakt) Supports(data domain.Serializable) bool {
+	collection, ok := data.(*domain.Collection)
+	return ok && (collection.Category == "films" || collection.Category == "series")
+}
+
+func (f *Trakt) Format(data domain.Serializable, writer io.Writer) error {
+	if !f.Supports(data) {
+		return fmt.Errorf("%s can't be formatted for Trakt", data.Slug())
+	}
+	category := data.(*domain.Collection).Category
+
+	history, ratings, watchlist := &traktItems{}, &traktItems{}, &traktItems{}
+	for _, entry := range data.CSV() {
+		if entry.State == domain.StateWish {
+			watchlist.add(category, newTraktItem(entry))
+			continue
+		}
+
+		for _, item := range traktHistory(entry) {
+			history.add(category, item)
+		}
+
+		if entry.Rating > 0 {
+			item := newTraktItem(entry)
+			item.Rating = entry.Rating
+			if item.Rating > 10 {
+				item.Rating = 10
+			}
+			ratings.add(category, item)
+		}
+	}
+
+	export := &traktExport{}
+	if !history.empty() {
+		export.History = history
+	}
+	if !ratings.empty() {
+		export.Ratings = ratings
+	}
+	if !watchlist.empty() {
+		export.Watchlist = watchlist
+	}
+
+	encoder := json.NewEncoder(writer)
+	if f.pretty {
+		encoder.SetIndent("", "    ")
+	}
+	return encoder.Encode(export)
+}
+
+// newTraktItem returns the item of an entry, with its original title as it
+// is more likely to be known by Trakt
+func newTraktItem(entry *domain.Entry) *traktItem {
+	title := entry.Title
+	if entry.OriginalTitle != "" {
+		title = entry.OriginalTitle
+	}
+	return &traktItem{Title: title, Year: entry.Year}
+}
+
+// traktHistory returns the watches of an entry: its watched episodes, or
+// else an item per done date
+func traktHistory(entry *domain.Entry) []*traktItem {
+	if len(entry.Episodes) > 0 {
+		seasons := map[int]*traktSeason{}
+		item := newTraktItem(entry)
+		for _, episode := range entry.Episodes {
+			season, ok := seasons[episode.Season]
+			if !ok {
+				season = &traktSeason{Number: episode.Season}
+				seasons[episode.Season] = season
+				item.Seasons = append(item.Seasons, season)
+			}
+			season.Episodes = append(season.Episodes, &traktEpisode{episode.Number, traktWatchedAt(episode.WatchedDate)})
+		}
+		sort.Slice(item.Seasons, func(i, j int) bool {
+			return item.Seasons[i].Number < item.Seasons[j].Number
+		})
+		return []*traktItem{item}
+	}
+
+	if entry.State != domain.StateDone {
+		return nil
+	}
+
+	dates := entry.DoneDates
+	if len(dates) == 0 && entry.DoneDate != nil {
+		dates = []domain.PartialDate{*entry.DoneDate}
+	}
+	if len(dates) == 0 {
+		item := newTraktItem(entry)
+		item.WatchedAt = traktReleased
+		return []*traktItem{item}
+	}
+
+	items := make([]*traktItem, 0, len(dates))
+	for i := range dates {
+		item := newTraktItem(entry)
+		item.WatchedAt = traktWatchedAt(&dates[i])
+		items = append(items, item)
+	}
+	return items
+}
+
+// traktWatchedAt returns the watched_at of a date, or "released" if its day
+// is unknown
+func traktWatchedAt(date *domain.PartialDate) string {
+	if date == nil || date.Precision != domain.PrecisionDay {
+		return traktReleased
+	}
+	return date.String() + "T00:00:00.000Z"
+}
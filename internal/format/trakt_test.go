@@ -0,0 +1,93 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func TestTraktFilms(t *testing.T) {
+	dates := []domain.PartialDate{}
+	for _, raw := range []string{"2016-07-09", "2020-10-25"} {
+		date, _ := domain.ParsePartialDate(raw)
+		dates = append(dates, date)
+	}
+	partial, _ := domain.ParsePartialDate("2020-00-00")
+
+	done := domain.NewCollection([]*domain.Entry{
+		{ID: "1", Title: "La Cabane dans les bois", OriginalTitle: "The Cabin in the Woods", Year: 2012, Rating: 7, DoneDate: &dates[1], DoneDates: dates},
+		{ID: "2", Title: "Quelques minutes après minuit", Year: 2016, DoneDate: &partial},
+		{ID: "3", Title: "Tenet", Year: 2020, State: domain.StateWish},
+	}, "films", domain.StateDone, "mlcdf")
+
+	var b bytes.Buffer
+	if err := NewTrakt(false).Format(done, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	var export traktExport
+	if err := json.Unmarshal(b.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+
+	history := export.History.Movies
+	if len(history) != 3 {
+		t.Fatalf("expected 3 watches, got %d", len(history))
+	}
+	if history[0].Title != "The Cabin in the Woods" || history[0].Year != 2012 || history[0].WatchedAt != "2016-07-09T00:00:00.000Z" || history[1].WatchedAt != "2020-10-25T00:00:00.000Z" {
+		t.Errorf("unexpected watches %+v, %+v", history[0], history[1])
+	}
+	if history[2].WatchedAt != "released" {
+		t.Errorf("expected a watch of unknown day to be at the release, got %s", history[2].WatchedAt)
+	}
+
+	if ratings := export.Ratings.Movies; len(ratings) != 1 || ratings[0].Rating != 7 || ratings[0].WatchedAt != "" {
+		t.Errorf("unexpected ratings %+v", ratings)
+	}
+	if watchlist := export.Watchlist.Movies; len(watchlist) != 1 || watchlist[0].Title != "Tenet" {
+		t.Errorf("unexpected watchlist %+v", watchlist)
+	}
+	if export.History.Shows != nil {
+		t.Errorf("expected no shows")
+	}
+}
+
+func TestTraktSeries(t *testing.T) {
+	watched, _ := domain.ParsePartialDate("2020-12-04")
+	inProgress := domain.NewCollection([]*domain.Entry{
+		{ID: "8853524", Title: "The Fall", Year: 2013, Episodes: []*domain.Episode{
+			{ID: "3", Season: 2, Number: 1, WatchedDate: &watched},
+			{ID: "1", Season: 1, Number: 1, WatchedDate: &watched},
+			{ID: "2", Season: 1, Number: 2},
+		}},
+		{ID: "2", Title: "Dark", Year: 2017},
+	}, "series", domain.StateInProgress, "mlcdf")
+
+	var b bytes.Buffer
+	if err := NewTrakt(true).Format(inProgress, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	var export traktExport
+	if err := json.Unmarshal(b.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+
+	if export.Ratings != nil || export.Watchlist != nil {
+		t.Errorf("expected a history only, got %s", b.String())
+	}
+	shows := export.History.Shows
+	if len(shows) != 1 || len(shows[0].Seasons) != 2 {
+		t.Fatalf("expected the seasons of The Fall only, got %s", b.String())
+	}
+	first := shows[0].Seasons[0]
+	if first.Number != 1 || len(first.Episodes) != 2 || first.Episodes[0].WatchedAt != "2020-12-04T00:00:00.000Z" || first.Episodes[1].WatchedAt != "released" {
+		t.Errorf("unexpected season %+v", first)
+	}
+
+	if NewTrakt(false).Supports(domain.NewCollection(nil, "livres", domain.StateDone, "mlcdf")) {
+		t.Errorf("expected the books not to be supported")
+	}
+}
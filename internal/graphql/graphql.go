@@ -0,0 +1,672 @@
+// Package graphql scrapes the client-side rendered senscritique.com through
+// the GraphQL API its frontend fetches its data from.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/domain"
+	"go.mlcdf.fr/sc-backup/internal/logging"
+	"go.mlcdf.fr/sc-backup/internal/pool"
+	"go.mlcdf.fr/sc-backup/internal/ratelimit"
+	"go.mlcdf.fr/sc-backup/internal/retry"
+)
+
+// Endpoint is the GraphQL endpoint used by www.senscritique.com
+const Endpoint = "https://apollo.senscritique.com/"
+
+// DefaultConcurrency is the default number of queries sent at once
+const DefaultConcurrency = 20
+
+// pageSize is the number of products requested per query
+var pageSize = 100
+
+// universes maps the categories used in backups to the API universes
+var universes = map[string]string{
+	"films":     "movie",
+	"series":    "tvShow",
+	"bd":        "comicBook",
+	"livres":    "book",
+	"albums":    "album",
+	"morceaux":  "track",
+	"jeuxvideo": "game",
+}
+
+// actions maps the collection filters to the API product actions
+var actions = map[string]string{
+	domain.StateDone:       "DONE",
+	domain.StateWish:       "WISH",
+	domain.StateInProgress: "IN_PROGRESS",
+}
+
+var _ domain.Source = (*Client)(nil)
+
+// Client queries the GraphQL API
+type Client struct {
+	endpoint    string
+	http        *http.Client
+	userAgent   string
+	concurrency int
+	logger      logging.Logger
+	limiter     *ratelimit.Limiter
+	retry       retry.Policy
+
+	// sleep waits between two attempts. It is replaced in tests.
+	sleep func(context.Context, time.Duration) error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTransport sets the transport used to send the requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.http.Transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of each request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.http.Timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header of each request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithConcurrency sets the number of queries sent at once
+func WithConcurrency(concurrency int) Option {
+	return func(c *Client) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithLimiter shares a rate limiter with the other clients of the run. The
+// wait for the limiter doesn't count against the timeout of the requests.
+func WithLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithRetryPolicy sets how the failed requests are retried
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New returns a Client for the given endpoint, configured with the given
+// options
+func New(endpoint string, options ...Option) *Client {
+	c := &Client{
+		endpoint:    endpoint,
+		http:        &http.Client{Timeout: time.Second * 20},
+		concurrency: DefaultConcurrency,
+		logger:      logging.Default(),
+		retry:       retry.DefaultPolicy,
+		sleep:       ratelimit.Sleep,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	return c
+}
+
+// newRequest returns a request with the client's User-Agent
+func (c *Client) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+type gqlRequest struct {
+	OperationName string                 `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type gqlError struct {
+	Message string `json:"message"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+type person struct {
+	Name string `json:"name"`
+}
+
+type product struct {
+	ID               int    `json:"id"`
+	Title            string `json:"title"`
+	OriginalTitle    string `json:"originalTitle"`
+	YearOfProduction int    `json:"yearOfProduction"`
+	GenresInfos      []struct {
+		Label string `json:"label"`
+	} `json:"genresInfos"`
+	Directors   []person `json:"directors"`
+	Creators    []person `json:"creators"`
+	Authors     []person `json:"authors"`
+	Artists     []person `json:"artists"`
+	Developers  []person `json:"developers"`
+	GameSystems []struct {
+		Label string `json:"label"`
+	} `json:"gameSystems"`
+	Medias *struct {
+		Picture string `json:"picture"`
+	} `json:"medias"`
+	OtherUserInfos *struct {
+		Rating        int    `json:"rating"`
+		IsRecommended bool   `json:"isRecommended"`
+		DateDone      string `json:"dateDone"`
+	} `json:"otherUserInfos"`
+}
+
+// query sends a GraphQL operation and decodes its data into out
+func (c *Client) query(ctx context.Context, operation string, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(&gqlRequest{operation, query, variables})
+	if err != nil {
+		return err
+	}
+
+	res, err := retry.Do(ctx, c.retry, c.logger, c.sleep, func() (*http.Response, error) {
+		// the body is consumed by each attempt
+		req, err := c.newRequest(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		if _, err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		c.logger.Debug("POST %s %s %v", c.endpoint, operation, variables)
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to POST %s", operation)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("error: http %d for operation %s", res.StatusCode, operation)
+	}
+
+	var response gqlResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return errors.Wrapf(err, "failed to decode %s response", operation)
+	}
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("error: %s failed: %s", operation, strings.Join(messages, "; "))
+	}
+
+	return json.Unmarshal(response.Data, out)
+}
+
+// toEntry converts a product into an entry
+func toEntry(p *product) *domain.Entry {
+	entry := &domain.Entry{
+		ID:      strconv.Itoa(p.ID),
+		Title:   p.Title,
+		Year:    p.YearOfProduction,
+		Authors: make([]string, 0, 5),
+	}
+
+	if p.OriginalTitle != p.Title {
+		entry.OriginalTitle = p.OriginalTitle
+	}
+
+	for _, people := range [][]person{p.Directors, p.Creators, p.Authors, p.Artists, p.Developers} {
+		if len(people) == 0 {
+			continue
+		}
+		for _, person := range people {
+			entry.Authors = append(entry.Authors, person.Name)
+		}
+		break
+	}
+
+	if p.Medias != nil {
+		entry.Poster = p.Medias.Picture
+	}
+
+	for _, platform := range p.GameSystems {
+		entry.Platforms = append(entry.Platforms, platform.Label)
+	}
+
+	for _, genre := range p.GenresInfos {
+		if entry.Genres == nil {
+			entry.Genres = make([]string, 0, len(p.GenresInfos))
+		}
+		entry.Genres = append(entry.Genres, strings.Title(genre.Label))
+	}
+
+	if infos := p.OtherUserInfos; infos != nil {
+		entry.Rating = infos.Rating
+		entry.Favorite = infos.IsRecommended
+		if len(infos.DateDone) >= 10 {
+			if date, err := domain.ParsePartialDate(infos.DateDone[:10]); err == nil && !date.IsZero() {
+				entry.DoneDate = &date
+			}
+		}
+	}
+	return entry
+}
+
+// paginate fetches the pages following the first one and merges their entries
+func (c *Client) paginate(ctx context.Context, entries []*domain.Entry, total int, fetch func(ctx context.Context, offset int) ([]*domain.Entry, error)) ([]*domain.Entry, error) {
+	nbOfPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	if nbOfPages <= 1 {
+		return entries, nil
+	}
+
+	tasks := []*pool.Task{}
+	for i := 1; i < nbOfPages; i++ {
+		offset := i * pageSize
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			return fetch(ctx, offset)
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+
+	return p.Merge(entries)
+}
+
+// ValidateUser checks that the user exists
+func (c *Client) ValidateUser(ctx context.Context, username string) error {
+	var data struct {
+		User *struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+
+	err := c.query(ctx, "User", userQuery, map[string]interface{}{"username": username}, &data)
+	if err != nil {
+		return errors.Wrap(err, "failed to validate user")
+	}
+
+	if data.User == nil {
+		return fmt.Errorf("username %s does not exist or has a limited profil", username)
+	}
+	return nil
+}
+
+// Collection fetches a user collection for the given category and filter
+func (c *Client) Collection(ctx context.Context, username string, category string, filter string) (*domain.Collection, error) {
+	universe, ok := universes[category]
+	if !ok {
+		return nil, fmt.Errorf("unknown category %s", category)
+	}
+
+	action, ok := actions[filter]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter %s", filter)
+	}
+
+	fetch := func(ctx context.Context, offset int) (int, []*domain.Entry, error) {
+		var data struct {
+			User *struct {
+				Collection struct {
+					Total    int        `json:"total"`
+					Products []*product `json:"products"`
+				} `json:"collection"`
+			} `json:"user"`
+		}
+
+		variables := map[string]interface{}{
+			"username": username,
+			"universe": universe,
+			"action":   action,
+			"limit":    pageSize,
+			"offset":   offset,
+		}
+
+		err := c.query(ctx, "UserCollection", collectionQuery, variables, &data)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if data.User == nil {
+			return 0, nil, fmt.Errorf("username %s does not exist or has a limited profil", username)
+		}
+
+		entries := make([]*domain.Entry, 0, len(data.User.Collection.Products))
+		for _, p := range data.User.Collection.Products {
+			entries = append(entries, toEntry(p))
+		}
+		return data.User.Collection.Total, entries, nil
+	}
+
+	total, entries, err := fetch(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err = c.paginate(ctx, entries, total, func(ctx context.Context, offset int) ([]*domain.Entry, error) {
+		_, entries, err := fetch(ctx, offset)
+		return entries, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewCollection(entries, category, filter, username), nil
+}
+
+var listIDRegexp = regexp.MustCompile(`/liste/[^/]+/(\d+)`)
+
+// listID extracts the list ID from a list URL
+func listID(url string) (int, error) {
+	matches := listIDRegexp.FindStringSubmatch(url)
+	if len(matches) != 2 {
+		return 0, fmt.Errorf("failed to find the list ID in %s", url)
+	}
+	return strconv.Atoi(matches[1])
+}
+
+// List fetches a list
+func (c *Client) List(ctx context.Context, url string) (*domain.List, error) {
+	id, err := listID(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		List *struct {
+			Title         string `json:"title"`
+			Description   string `json:"description"`
+			ProductsCount int    `json:"productsCount"`
+			Author        struct {
+				Username string `json:"username"`
+			} `json:"author"`
+		} `json:"list"`
+	}
+
+	err = c.query(ctx, "List", listQuery, map[string]interface{}{"id": id}, &info)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.List == nil {
+		return nil, fmt.Errorf("list %s does not exist", url)
+	}
+
+	if info.List.Title == "" {
+		return nil, errors.Wrapf(fmt.Errorf("title cannot be empty"), "%s", url)
+	}
+
+	fetch := func(ctx context.Context, offset int) ([]*domain.Entry, error) {
+		var data struct {
+			List struct {
+				Products []struct {
+					Annotation string   `json:"annotation"`
+					Product    *product `json:"product"`
+				} `json:"products"`
+			} `json:"list"`
+		}
+
+		variables := map[string]interface{}{
+			"id":       id,
+			"username": info.List.Author.Username,
+			"limit":    pageSize,
+			"offset":   offset,
+		}
+
+		err := c.query(ctx, "ListProducts", listProductsQuery, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]*domain.Entry, 0, len(data.List.Products))
+		for _, item := range data.List.Products {
+			entry := toEntry(item.Product)
+			entry.Comment = strings.TrimSpace(item.Annotation)
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+
+	entries, err := fetch(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err = c.paginate(ctx, entries, info.List.ProductsCount, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	if nbEntries := len(entries); nbEntries != info.List.ProductsCount {
+		return nil, fmt.Errorf("the list '%s' has %d entries, but only %d were found", info.List.Title, info.List.ProductsCount, nbEntries)
+	}
+
+	return domain.NewList(entries, info.List.Title, strings.TrimSpace(info.List.Description)), nil
+}
+
+// Journal fetches the done dates from a user's diary. The diary is queried
+// once per category, unless all of them are requested.
+func (c *Client) Journal(ctx context.Context, username string, categories []string) ([]*domain.Entry, error) {
+	if len(categories) == 0 || len(categories) == len(universes) {
+		return c.diary(ctx, username, "")
+	}
+
+	entries := make([]*domain.Entry, 0)
+	for _, category := range categories {
+		universe, ok := universes[category]
+		if !ok {
+			return nil, fmt.Errorf("unknown category %s", category)
+		}
+
+		categoryEntries, err := c.diary(ctx, username, universe)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, categoryEntries...)
+	}
+	return entries, nil
+}
+
+// diary fetches the done dates of a universe, or of all of them if universe
+// is empty
+func (c *Client) diary(ctx context.Context, username string, universe string) ([]*domain.Entry, error) {
+	fetch := func(ctx context.Context, offset int) (int, []*domain.Entry, error) {
+		var data struct {
+			User *struct {
+				Diary struct {
+					Total    int        `json:"total"`
+					Products []*product `json:"products"`
+				} `json:"diary"`
+			} `json:"user"`
+		}
+
+		variables := map[string]interface{}{
+			"username": username,
+			"limit":    pageSize,
+			"offset":   offset,
+		}
+		if universe != "" {
+			variables["universe"] = universe
+		}
+
+		err := c.query(ctx, "UserDiary", diaryQuery, variables, &data)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if data.User == nil {
+			return 0, nil, fmt.Errorf("username %s does not exist or has a limited profil", username)
+		}
+
+		entries := make([]*domain.Entry, 0, len(data.User.Diary.Products))
+		for _, p := range data.User.Diary.Products {
+			entry := toEntry(p)
+			entries = append(entries, &domain.Entry{ID: entry.ID, DoneDate: entry.DoneDate})
+		}
+		return data.User.Diary.Total, entries, nil
+	}
+
+	total, entries, err := fetch(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.paginate(ctx, entries, total, func(ctx context.Context, offset int) ([]*domain.Entry, error) {
+		_, entries, err := fetch(ctx, offset)
+		return entries, err
+	})
+}
+
+var _ domain.ProductSource = (*Client)(nil)
+var _ domain.AssetSource = (*Client)(nil)
+
+// Asset downloads an asset, like a poster
+func (c *Client) Asset(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := retry.Do(ctx, c.retry, c.logger, c.sleep, func() (*http.Response, error) {
+		if _, err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		c.logger.Debug("GET %s", url)
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to GET %s", url)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		res.Body.Close()
+		return nil, fmt.Errorf("error: http %d for url %s", res.StatusCode, url)
+	}
+	return res.Body, nil
+}
+
+// Products fetches the details of the given products
+func (c *Client) Products(ctx context.Context, ids []string) ([]*domain.Product, error) {
+	tasks := make([]*pool.Task, 0, len(ids))
+	for _, id := range ids {
+		id := id
+		tasks = append(tasks, pool.NewTask(func(ctx context.Context) (interface{}, error) {
+			return c.product(ctx, id)
+		}))
+	}
+
+	p := pool.NewPool(tasks, c.concurrency)
+	p.Run(ctx)
+
+	products := make([]*domain.Product, 0, len(ids))
+	for _, task := range p.Tasks {
+		if product, ok := task.Out.(*domain.Product); ok && task.Err == nil {
+			products = append(products, product)
+		}
+	}
+	return products, p.Err()
+}
+
+func (c *Client) product(ctx context.Context, id string) (*domain.Product, error) {
+	productID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID %s", id)
+	}
+
+	var data struct {
+		Product *struct {
+			URL       string `json:"url"`
+			Duration  int    `json:"duration"`
+			Countries []struct {
+				Name string `json:"name"`
+			} `json:"countries"`
+			Synopsis string `json:"synopsis"`
+			Medias   *struct {
+				Picture string `json:"picture"`
+			} `json:"medias"`
+			Stats *struct {
+				AverageRating float64 `json:"averageRating"`
+			} `json:"stats"`
+		} `json:"product"`
+	}
+
+	if err := c.query(ctx, "Product", productQuery, map[string]interface{}{"id": productID}, &data); err != nil {
+		return nil, err
+	}
+
+	if data.Product == nil {
+		return nil, fmt.Errorf("product %s does not exist", id)
+	}
+
+	product := &domain.Product{
+		ID:       id,
+		URL:      data.Product.URL,
+		Synopsis: strings.TrimSpace(data.Product.Synopsis),
+	}
+
+	// the duration is in seconds
+	product.Runtime = data.Product.Duration / 60
+
+	for _, country := range data.Product.Countries {
+		product.Countries = append(product.Countries, country.Name)
+	}
+	if data.Product.Medias != nil {
+		product.CoverURL = data.Product.Medias.Picture
+	}
+	if data.Product.Stats != nil {
+		product.AverageRating = data.Product.Stats.AverageRating
+	}
+	return product, nil
+}
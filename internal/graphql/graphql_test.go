@@ -0,0 +1,293 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureKeys are the variables, in order, used to name the recorded payloads
+var fixtureKeys = []string{"id", "username", "universe", "action", "offset"}
+
+// newServer returns a stand-in for the GraphQL API that serves the payloads
+// recorded in testdata.
+func newServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name := []string{req.OperationName}
+		for _, key := range fixtureKeys {
+			if value, ok := req.Variables[key]; ok {
+				name = append(name, fmt.Sprint(value))
+			}
+		}
+
+		payload, err := os.ReadFile(filepath.Join("testdata", strings.Join(name, "-")+".json"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func withPageSize(t *testing.T, size int) {
+	previous := pageSize
+	pageSize = size
+	t.Cleanup(func() { pageSize = previous })
+}
+
+func TestValidateUser(t *testing.T) {
+	client := New(newServer(t).URL)
+
+	username := "username-that-does-not-exists"
+	if err := client.ValidateUser(context.Background(), username); err == nil {
+		t.Errorf("username %s should not exist", username)
+	}
+
+	username = "mlcdf"
+	if err := client.ValidateUser(context.Background(), username); err != nil {
+		t.Errorf("username %s should exist: %s", username, err)
+	}
+}
+
+func TestListID(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected int
+	}{
+		{"https://www.senscritique.com/liste/Vu_au_cinema/363578", 363578},
+		{"https://www.senscritique.com/liste/Vu_au_cinema/363578/page-2", 363578},
+		{"https://www.senscritique.com/liste/vu_au_cinema/363578#page-1/", 363578},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.url, func(t *testing.T) {
+			id, err := listID(tC.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tC.expected {
+				t.Errorf("expected %d, got %d", tC.expected, id)
+			}
+		})
+	}
+
+	if _, err := listID("https://www.senscritique.com/mlcdf"); err == nil {
+		t.Errorf("expected an error for a URL without a list ID")
+	}
+}
+
+func TestCollection(t *testing.T) {
+	withPageSize(t, 2)
+	client := New(newServer(t).URL)
+
+	collection, err := client.Collection(context.Background(), "mlcdf", "films", "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if collection.Slug() != "films-done" {
+		t.Errorf("expected slug films-done, got %s", collection.Slug())
+	}
+
+	if l := len(collection.Entries); l != 3 {
+		t.Fatalf("expected 3 entries, got %d", l)
+	}
+
+	entry := collection.Entries[1]
+	if entry.ID != "491576" {
+		t.Errorf("expected ID 491576, got %s", entry.ID)
+	}
+	if entry.OriginalTitle != "The Cabin in the Woods" {
+		t.Errorf("unexpected original title %s", entry.OriginalTitle)
+	}
+	if entry.DoneDate == nil || entry.DoneDate.String() != "2020-12-04" {
+		t.Errorf("expected done date 2020-12-04, got %s", entry.DoneDate)
+	}
+	if entry.State != "done" {
+		t.Errorf("expected state done, got %s", entry.State)
+	}
+	if !entry.Favorite {
+		t.Errorf("expected %s to be a favorite", entry.Title)
+	}
+	if len(entry.Authors) != 1 || entry.Authors[0] != "Drew Goddard" {
+		t.Errorf("unexpected authors %v", entry.Authors)
+	}
+	if len(entry.Genres) != 1 || entry.Genres[0] != "Épouvante-Horreur" {
+		t.Errorf("unexpected genres %v", entry.Genres)
+	}
+
+	if last := collection.Entries[2]; last.OriginalTitle != "" || last.Rating != 8 {
+		t.Errorf("unexpected last entry %+v", last)
+	}
+
+	if _, err := client.Collection(context.Background(), "mlcdf", "podcasts", "done"); err == nil {
+		t.Errorf("expected an error for an unknown category")
+	}
+}
+
+func TestList(t *testing.T) {
+	withPageSize(t, 2)
+	client := New(newServer(t).URL)
+
+	list, err := client.List(context.Background(), "https://www.senscritique.com/liste/Vu_au_cinema/363578")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Slug() != "vu-au-cinema" {
+		t.Errorf("expected slug vu-au-cinema, got %s", list.Slug())
+	}
+
+	if expectedDescription := "Depuis le 1er janvier 2014."; list.Description != expectedDescription {
+		t.Errorf("expected description '%s', got '%s'", expectedDescription, list.Description)
+	}
+
+	if l := len(list.Entries); l != 3 {
+		t.Fatalf("expected 3 entries, got %d", l)
+	}
+
+	entry := list.Entries[0]
+	if !entry.Favorite {
+		t.Errorf("expected %s to be a favorite", entry.Title)
+	}
+	if entry.Genres[0] != "Aventure" || entry.Genres[1] != "Comédie" {
+		t.Errorf("unexpected genres %v", entry.Genres)
+	}
+	if entry.DoneDate != nil {
+		t.Errorf("expected no done date, got %s", entry.DoneDate)
+	}
+
+	if comment := list.Entries[1].Comment; comment != "Vu en 3D." {
+		t.Errorf("expected comment 'Vu en 3D.', got '%s'", comment)
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	client := New(newServer(t).URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.Collection(ctx, "mlcdf", "films", "done"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the query to be canceled, got %v", err)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	client := New(newServer(t).URL)
+
+	entries, err := client.Journal(context.Background(), "mlcdf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(entries); l != 2 {
+		t.Fatalf("expected 2 entries, got %d", l)
+	}
+
+	if entry := entries[1]; entry.ID != "388729" || entry.DoneDate == nil || entry.DoneDate.String() != "2020-10-25" || entry.Title != "" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	entries, err = client.Journal(context.Background(), "mlcdf", []string{"films"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(entries); l != 1 || entries[0].ID != "491576" {
+		t.Errorf("expected the films of the journal only, got %d entries", l)
+	}
+}
+
+func TestProducts(t *testing.T) {
+	client := New(newServer(t).URL)
+
+	products, err := client.Products(context.Background(), []string{"491576", "1"})
+	if err == nil {
+		t.Errorf("expected an error for an unknown product")
+	}
+
+	if l := len(products); l != 1 {
+		t.Fatalf("expected 1 product, got %d", l)
+	}
+
+	product := products[0]
+	if product.ID != "491576" || product.Runtime != 95 || product.AverageRating != 6.6 {
+		t.Errorf("unexpected product %+v", product)
+	}
+	if len(product.Countries) != 1 || product.Countries[0] != "États-Unis" {
+		t.Errorf("unexpected countries %v", product.Countries)
+	}
+	if !strings.HasSuffix(product.CoverURL, ".jpg") || product.Synopsis == "" {
+		t.Errorf("unexpected product %+v", product)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Write([]byte(`{"data":{"user":{"username":"mlcdf"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(server.URL, WithUserAgent("sc-backup/test"))
+	if err := client.ValidateUser(context.Background(), "mlcdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if userAgent != "sc-backup/test" {
+		t.Errorf("expected the User-Agent sc-backup/test, got %s", userAgent)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		var req gqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OperationName != "User" {
+			http.Error(w, "the body should be sent again", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":{"user":{"username":"mlcdf"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var delays []time.Duration
+	client := New(server.URL)
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+
+	if err := client.ValidateUser(context.Background(), "mlcdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 || len(delays) != 1 || delays[0] != 3*time.Second {
+		t.Errorf("expected a single retry after 3s, got %d calls and delays %v", calls, delays)
+	}
+}
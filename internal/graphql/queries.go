@@ -0,0 +1,83 @@
+package graphql
+
+// productFields lists the product fields shared by every query. The
+// otherUserInfos field expects a $username variable.
+const productFields = `
+	id
+	title
+	originalTitle
+	yearOfProduction
+	genresInfos { label }
+	directors { name }
+	creators { name }
+	authors { name }
+	artists { name }
+	developers { name }
+	gameSystems { label }
+	medias { picture }
+	otherUserInfos(username: $username) {
+		rating
+		isRecommended
+		dateDone
+	}`
+
+const userQuery = `query User($username: String!) {
+	user(username: $username) {
+		username
+	}
+}`
+
+const collectionQuery = `query UserCollection($username: String!, $universe: String, $action: ProductAction, $limit: Int, $offset: Int) {
+	user(username: $username) {
+		collection(universe: $universe, action: $action, limit: $limit, offset: $offset) {
+			total
+			products {` + productFields + `
+			}
+		}
+	}
+}`
+
+const listQuery = `query List($id: Int!) {
+	list(id: $id) {
+		title
+		description
+		productsCount
+		author { username }
+	}
+}`
+
+const listProductsQuery = `query ListProducts($id: Int!, $username: String!, $limit: Int, $offset: Int) {
+	list(id: $id) {
+		products(limit: $limit, offset: $offset) {
+			annotation
+			product {` + productFields + `
+			}
+		}
+	}
+}`
+
+const diaryQuery = `query UserDiary($username: String!, $universe: String, $limit: Int, $offset: Int) {
+	user(username: $username) {
+		diary(universe: $universe, limit: $limit, offset: $offset) {
+			total
+			products {
+				id
+				otherUserInfos(username: $username) {
+					dateDone
+				}
+			}
+		}
+	}
+}`
+
+const productQuery = `query Product($id: Int!) {
+	product(id: $id) {
+		id
+		url
+		duration
+		countries { name }
+		synopsis
+		medias { picture }
+		stats { averageRating }
+	}
+}`
@@ -24,3 +24,24 @@ func Debug(format string, v ...interface{}) {
 		fmt.Fprintf(os.Stderr, format+"\n", v...)
 	}
 }
+
+// Logger is implemented by the types the scrapers log to
+type Logger interface {
+	Info(format string, v ...interface{})
+	Debug(format string, v ...interface{})
+}
+
+type stderr struct{}
+
+func (stderr) Info(format string, v ...interface{}) {
+	Info(format, v...)
+}
+
+func (stderr) Debug(format string, v ...interface{}) {
+	Debug(format, v...)
+}
+
+// Default returns a Logger that prints to stderr, like Info and Debug
+func Default() Logger {
+	return stderr{}
+}
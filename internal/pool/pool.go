@@ -1,13 +1,14 @@
 package pool
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"go.mlcdf.fr/sc-backup/internal/domain"
 )
 
-type RunFunc func() (interface{}, error)
+type RunFunc func(ctx context.Context) (interface{}, error)
 
 // Task encapsulates a work item that should go in a work
 // pool.
@@ -28,8 +29,8 @@ func NewTask(f RunFunc) *Task {
 
 // Run runs a Task and does appropriate accounting via a
 // given sync.WorkGroup.
-func (t *Task) Run(wg *sync.WaitGroup) {
-	t.Out, t.Err = t.Func()
+func (t *Task) Run(ctx context.Context, wg *sync.WaitGroup) {
+	t.Out, t.Err = t.Func(ctx)
 	wg.Done()
 }
 
@@ -54,15 +55,25 @@ func NewPool(tasks []*Task, concurrency int) *Pool {
 }
 
 // Run runs all work within the pool and blocks until it's
-// finished.
-func (p *Pool) Run() {
+// finished. Once ctx is done, the remaining tasks are not started
+// and fail with the context error, while the running ones are
+// waited for.
+func (p *Pool) Run(ctx context.Context) {
 	for i := 0; i < p.concurrency; i++ {
-		go p.work()
+		go p.work(ctx)
 	}
 
 	p.wg.Add(len(p.Tasks))
 	for _, task := range p.Tasks {
-		p.tasksChan <- task
+		if ctx.Err() == nil {
+			select {
+			case p.tasksChan <- task:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		task.Err = ctx.Err()
+		p.wg.Done()
 	}
 
 	// all workers return
@@ -72,19 +83,50 @@ func (p *Pool) Run() {
 }
 
 // The work loop for any single goroutine.
-func (p *Pool) work() {
+func (p *Pool) work(ctx context.Context) {
 	for task := range p.tasksChan {
-		task.Run(&p.wg)
+		task.Run(ctx, &p.wg)
 	}
 }
 
-// Merge the tasks result
-func (p *Pool) Merge(entries []*domain.Entry) ([]*domain.Entry, error) {
+// Errors holds the errors of all the failed tasks of a pool
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
+}
+
+// Unwrap returns the first error
+func (e Errors) Unwrap() error {
+	return e[0]
+}
+
+// Err returns the errors of the failed tasks as Errors, or nil if all the
+// tasks succeeded
+func (p *Pool) Err() error {
+	var errs Errors
 	for _, task := range p.Tasks {
 		if task.Err != nil {
-			return nil, task.Err
+			errs = append(errs, task.Err)
 		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
+// Merge the tasks result. If some tasks failed, it returns their
+// errors as Errors.
+func (p *Pool) Merge(entries []*domain.Entry) ([]*domain.Entry, error) {
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, task := range p.Tasks {
 		_out, ok := task.Out.([]*domain.Entry)
 		if !ok {
 			return nil, fmt.Errorf("critical: failed to cast to []*Entry. Please open a bug report at https://go.mlcdf.fr/sc-backup")
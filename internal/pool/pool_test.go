@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go.mlcdf.fr/sc-backup/internal/domain"
+)
+
+func TestMergeKeepsTaskOrder(t *testing.T) {
+	tasks := []*Task{}
+	for _, id := range []string{"2", "3", "4"} {
+		id := id
+		tasks = append(tasks, NewTask(func(ctx context.Context) (interface{}, error) {
+			return []*domain.Entry{{ID: id}}, nil
+		}))
+	}
+
+	p := NewPool(tasks, 3)
+	p.Run(context.Background())
+
+	entries, err := p.Merge([]*domain.Entry{{ID: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entry := range entries {
+		if expected := string(rune('1' + i)); entry.ID != expected {
+			t.Errorf("expected entry %s at index %d, got %s", expected, i, entry.ID)
+		}
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var started int32
+	tasks := []*Task{}
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, NewTask(func(ctx context.Context) (interface{}, error) {
+			if atomic.AddInt32(&started, 1) == 2 {
+				cancel()
+			}
+			return []*domain.Entry{}, nil
+		}))
+	}
+
+	p := NewPool(tasks, 1)
+	p.Run(ctx)
+
+	if started > 3 {
+		t.Errorf("expected the pool to stop after the cancellation, %d tasks started", started)
+	}
+
+	if !errors.Is(tasks[len(tasks)-1].Err, context.Canceled) {
+		t.Errorf("expected the last task to be canceled, got %v", tasks[len(tasks)-1].Err)
+	}
+
+	if _, err := p.Merge(nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Merge to return the cancellation, got %v", err)
+	}
+}
+
+func TestMergeReturnsAllErrors(t *testing.T) {
+	tasks := []*Task{}
+	for i := 0; i < 4; i++ {
+		i := i
+		tasks = append(tasks, NewTask(func(ctx context.Context) (interface{}, error) {
+			if i%2 == 1 {
+				return nil, errors.New("http 503")
+			}
+			return []*domain.Entry{}, nil
+		}))
+	}
+
+	p := NewPool(tasks, 2)
+	p.Run(context.Background())
+
+	_, err := p.Merge(nil)
+
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+
+	if expected := "http 503 (and 1 more errors)"; err.Error() != expected {
+		t.Errorf("expected %s, got %s", expected, err)
+	}
+}
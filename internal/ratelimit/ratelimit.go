@@ -0,0 +1,141 @@
+// Package ratelimit provides a token bucket shared by all the requests of a
+// run, so that the worker pools don't hammer senscritique.com. The clients
+// wait for it before each attempt, outside of the timeout of the request.
+package ratelimit
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket. A nil Limiter or a Limiter with a rate of zero
+// never waits.
+type Limiter struct {
+	rate   float64
+	burst  int
+	jitter time.Duration
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	requests int
+	waited   time.Duration
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// New returns a Limiter allowing rate requests per second with bursts of
+// burst requests. Each wait is extended by a random duration up to jitter.
+func New(rate float64, burst int, jitter time.Duration) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  burst,
+		jitter: jitter,
+		tokens: float64(burst),
+		now:    time.Now,
+		sleep:  Sleep,
+	}
+}
+
+// Sleep pauses for d or until ctx is done, in which case it returns the
+// context error
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests++
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	if l.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(l.jitter)))
+	}
+	return delay
+}
+
+// done records the wait of a reserved token, or gives the token back if the
+// request was canceled during the wait
+func (l *Limiter) done(delay time.Duration, canceled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if canceled {
+		l.requests--
+		if l.tokens < float64(l.burst) {
+			l.tokens++
+		}
+		return
+	}
+	l.waited += delay
+}
+
+// Wait blocks until a request is allowed and returns how long it waited.
+// It returns early with the context error if ctx is done, in which case the
+// request is neither allowed nor counted.
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, ctx.Err()
+	}
+
+	delay := l.reserve()
+	var err error
+	if delay > 0 {
+		err = l.sleep(ctx, delay)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	l.done(delay, err != nil)
+	if err != nil {
+		return 0, err
+	}
+	return delay, nil
+}
+
+// Stats returns the number of requests and how long they waited in total
+func (l *Limiter) Stats() (int, time.Duration) {
+	if l == nil {
+		return 0, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.requests, l.waited
+}
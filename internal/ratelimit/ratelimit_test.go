@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter driven by a fake clock that only advances
+// when the limiter sleeps.
+func newTestLimiter(rate float64, burst int) *Limiter {
+	l := New(rate, burst, 0)
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		now = now.Add(d)
+		return ctx.Err()
+	}
+	return l
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l := newTestLimiter(2, 3)
+
+	for i := 0; i < 3; i++ {
+		if waited, _ := l.Wait(context.Background()); waited != 0 {
+			t.Errorf("request %d should not wait, waited %s", i, waited)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if waited, _ := l.Wait(context.Background()); waited != 500*time.Millisecond {
+			t.Errorf("request %d should wait 500ms, waited %s", i+3, waited)
+		}
+	}
+
+	requests, waited := l.Stats()
+	if requests != 6 || waited != 1500*time.Millisecond {
+		t.Errorf("expected 6 requests and 1.5s waited, got %d and %s", requests, waited)
+	}
+}
+
+func TestLimiterRefills(t *testing.T) {
+	l := newTestLimiter(1, 2)
+	l.Wait(context.Background())
+	l.Wait(context.Background())
+	l.sleep(context.Background(), 10*time.Second)
+
+	for i := 0; i < 2; i++ {
+		if waited, _ := l.Wait(context.Background()); waited != 0 {
+			t.Errorf("request %d should not wait after a refill, waited %s", i, waited)
+		}
+	}
+	if waited, _ := l.Wait(context.Background()); waited != time.Second {
+		t.Errorf("the refill should be capped at the burst size, waited %s", waited)
+	}
+}
+
+func TestLimiterJitter(t *testing.T) {
+	l := newTestLimiter(1, 1)
+	l.jitter = 100 * time.Millisecond
+	l.Wait(context.Background())
+
+	if waited, _ := l.Wait(context.Background()); waited < time.Second || waited >= 1100*time.Millisecond {
+		t.Errorf("expected a wait between 1s and 1.1s, got %s", waited)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	var l *Limiter
+	if waited, _ := l.Wait(context.Background()); waited != 0 {
+		t.Errorf("a nil limiter should not wait")
+	}
+
+	l = newTestLimiter(0, 1)
+	for i := 0; i < 10; i++ {
+		if waited, _ := l.Wait(context.Background()); waited != 0 {
+			t.Errorf("a limiter without rate should not wait")
+		}
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	l := New(1, 1, 0)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait to be canceled, got %v", err)
+	}
+}
+
+func TestWaitCanceledGivesTheTokenBack(t *testing.T) {
+	l := newTestLimiter(1, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	if _, err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be canceled, got %v", err)
+	}
+
+	if requests, waited := l.Stats(); requests != 1 || waited != 0 {
+		t.Errorf("the canceled wait should not be counted, got %d requests and %s waited", requests, waited)
+	}
+	if l.tokens != 0 {
+		t.Errorf("the token should be given back, got %f tokens", l.tokens)
+	}
+}
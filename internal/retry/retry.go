@@ -0,0 +1,145 @@
+// Package retry retries the requests that failed with a transport error or
+// a transient status, with a jittered exponential backoff honoring the
+// Retry-After header.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mlcdf.fr/sc-backup/internal/logging"
+)
+
+// Policy tells how the failed requests are retried
+type Policy struct {
+	// MaxAttempts is the maximum number of attempts per request, the first
+	// one included
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles at each
+	// attempt and is jittered.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, including the one
+	// asked by a Retry-After header
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is the retry policy of the clients unless another one is set
+var DefaultPolicy = Policy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// Do calls send until it returns a response whose status is not retryable,
+// retrying the transport errors and the retryable statuses according to
+// policy, until ctx is done. sleep waits between two attempts, e.g.
+// ratelimit.Sleep. The caller handles the status of the returned response.
+//
+// send is called once per attempt, so it must build a new request each time
+// if the request has a body.
+func Do(ctx context.Context, policy Policy, logger logging.Logger, sleep func(context.Context, time.Duration) error, send func() (*http.Response, error)) (*http.Response, error) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var res *http.Response
+		res, err = send()
+		if ctx.Err() != nil {
+			if res != nil {
+				res.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		var delay time.Duration
+		if err == nil {
+			if !IsRetryable(res.StatusCode) {
+				return res, nil
+			}
+
+			err = fmt.Errorf("error: http %d for url %s", res.StatusCode, res.Request.URL)
+			delay, _ = retryAfter(res)
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		if attempt >= policy.MaxAttempts {
+			break
+		}
+
+		if backoff := policy.backoff(attempt); delay < backoff {
+			delay = backoff
+		}
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+
+		logger.Debug("%s: retrying in %s (attempt %d/%d)", err, delay.Round(time.Millisecond), attempt+1, policy.MaxAttempts)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.MaxAttempts > 1 {
+		return nil, errors.Wrapf(err, "gave up after %d attempts", policy.MaxAttempts)
+	}
+	return nil, err
+}
+
+// IsRetryable tells whether a request that failed with this status code
+// may succeed later
+func IsRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header, either a number of seconds or an
+// HTTP date. It returns false if the header is missing or invalid.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// backoff returns the jittered delay before the given retry, starting at 1
+func (p Policy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << uint(retry-1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// keep at least half of the delay so that the retries stay spread out
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mlcdf.fr/sc-backup/internal/logging"
+)
+
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"soon", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.value, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			res.Header.Set("Retry-After", tC.value)
+
+			delay, ok := retryAfter(res)
+			if delay != tC.expected || ok != tC.ok {
+				t.Errorf("expected (%s, %t), got (%s, %t)", tC.expected, tC.ok, delay, ok)
+			}
+		})
+	}
+}
+
+// responses returns a send function answering with the given statuses in
+// turn, a status of 0 standing for a transport error, and the number of calls
+func responses(statuses ...int) (func() (*http.Response, error), *int) {
+	calls := 0
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/mlcdf", nil)
+	return func() (*http.Response, error) {
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+
+		if status == 0 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}, &calls
+}
+
+// recordSleep returns a sleep function recording the delays instead of
+// sleeping
+func recordSleep(delays *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+}
+
+func TestDo(t *testing.T) {
+	testCases := []struct {
+		name        string
+		statuses    []int
+		maxAttempts int
+		status      int
+		calls       int
+	}{
+		{"success", []int{200}, 4, 200, 1},
+		{"retryable statuses", []int{503, 429, 502, 200}, 4, 200, 4},
+		{"transport errors", []int{0, 0, 200}, 4, 200, 3},
+		{"fatal status", []int{404, 200}, 4, 404, 1},
+		{"gives up", []int{503}, 3, 0, 3},
+		{"single attempt", []int{503, 200}, 1, 0, 1},
+		{"no attempt is one attempt", []int{0, 200}, 0, 0, 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			send, calls := responses(tC.statuses...)
+			policy := Policy{MaxAttempts: tC.maxAttempts, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+			var delays []time.Duration
+			res, err := Do(context.Background(), policy, logging.Default(), recordSleep(&delays), send)
+
+			if tC.status == 0 && err == nil {
+				t.Errorf("expected an error, got http %d", res.StatusCode)
+			}
+			if tC.status != 0 && (err != nil || res.StatusCode != tC.status) {
+				t.Errorf("expected http %d, got %v (%v)", tC.status, res, err)
+			}
+			if *calls != tC.calls {
+				t.Errorf("expected %d calls, got %d", tC.calls, *calls)
+			}
+			if len(delays) != tC.calls-1 {
+				t.Errorf("expected a delay between each call, got %v", delays)
+			}
+		})
+	}
+}
+
+func TestDoBackoff(t *testing.T) {
+	send, _ := responses(503, 503, 503, 503, 503, 200)
+	policy := Policy{MaxAttempts: 6, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	var delays []time.Duration
+	if _, err := Do(context.Background(), policy, logging.Default(), recordSleep(&delays), send); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, delay := range delays {
+		max := 100 * time.Millisecond << uint(i)
+		if max > time.Second {
+			max = time.Second
+		}
+		if delay < max/2 || delay > max {
+			t.Errorf("delay %d should be between %s and %s, got %s", i, max/2, max, delay)
+		}
+	}
+}
+
+func TestDoRetryAfterIsCapped(t *testing.T) {
+	calls := 0
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/mlcdf", nil)
+	send := func() (*http.Response, error) {
+		calls++
+		res := &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+		if calls == 1 {
+			res.StatusCode = http.StatusTooManyRequests
+			res.Header.Set("Retry-After", "3600")
+		}
+		return res, nil
+	}
+
+	var delays []time.Duration
+	policy := Policy{MaxAttempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Minute}
+	if _, err := Do(context.Background(), policy, logging.Default(), recordSleep(&delays), send); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(delays) != 1 || delays[0] != time.Minute {
+		t.Errorf("expected the Retry-After to be capped at 1m, got %v", delays)
+	}
+}
+
+func TestDoCanceledDuringBackoff(t *testing.T) {
+	send, calls := responses(503)
+	ctx, cancel := context.WithCancel(context.Background())
+	sleep := func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	if _, err := Do(ctx, DefaultPolicy, logging.Default(), sleep, send); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the request to be canceled, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected a single call, got %d", *calls)
+	}
+}
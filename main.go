@@ -1,39 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"strings"
+	"syscall"
 	"time"
 
 	"go.mlcdf.fr/sc-backup/internal/backend"
 	"go.mlcdf.fr/sc-backup/internal/backup"
+	"go.mlcdf.fr/sc-backup/internal/cache"
+	"go.mlcdf.fr/sc-backup/internal/checkpoint"
 	"go.mlcdf.fr/sc-backup/internal/domain"
 	"go.mlcdf.fr/sc-backup/internal/format"
+	"go.mlcdf.fr/sc-backup/internal/graphql"
 	"go.mlcdf.fr/sc-backup/internal/logging"
+	"go.mlcdf.fr/sc-backup/internal/ratelimit"
+	"go.mlcdf.fr/sc-backup/internal/retry"
 )
 
 const usage = `Usage:
     sc-backup --collection [USERNAME]
     sc-backup --list [URL]
+    sc-backup --lists [USERNAME]
 
 Options:
     -c, --collection USERNAME   Backup a user's collection
     -l, --list URL              Backup a list
+    --lists USERNAME            Backup all the lists of a user into a lists
+                                directory, with an index of them (legacy source)
+    --category LIST             Comma-separated categories to backup, among
+                                films, series, bd, livres, albums, morceaux and
+                                jeuxvideo. Defaults to all of them
+    --filter LIST               Comma-separated filters to backup, among done,
+                                wish and in-progress (series, bd, livres and
+                                jeuxvideo only, graphql source). Defaults to
+                                done and wish
+    --reviews                   Also backup the reviews written by the user, linked
+                                to the entries by product ID (legacy source)
+    --social                    Also backup the scouts and the followers of the
+                                user (legacy source)
+    --enrich                    Add the runtime, countries, synopsis, cover, average
+                                rating and URL of their product page to the
+                                entries. The products are cached in the output
+                                directory and only fetched once
+    --images                    Download the posters of the entries into a covers
+                                directory in the output directory, shared by the
+                                collections and the lists. Posters already
+                                downloaded are skipped
     -o, --output PATH           Directory at which to backup the data. Defaults to ./output
-    -f, --format json|csv       Export format. Defaults to json
+    -s, --source legacy|graphql Website to scrape: the legacy server-side rendered
+                                website or the GraphQL API of the current one.
+                                Defaults to legacy
+    -f, --format FORMAT         Export format: json, csv, or the import format of
+                                letterboxd for the films and the lists, of
+                                goodreads or storygraph for the livres and bd, or
+                                of trakt for the films and series.
+                                Defaults to json
     -p, --pretty                Prettify the JSON exports
+    --csv-delimiter CHAR        Delimiter of the CSV columns, e.g. ";" or "tab".
+                                Defaults to ","
+    --csv-list-separator SEP    Separator of the values of the list columns, like
+                                the authors. Defaults to ";"
+    --csv-bom                   Start the CSV files with a UTF-8 BOM, for Excel
+    --csv-columns LIST          Comma-separated columns of the entries to write
+                                to the CSV files, in order. Defaults to all of them
+    --base-url URL              URL of the website or the GraphQL API to scrape,
+                                e.g. a mirror or a test server
+    --proxy URL                 Send the requests through a proxy
+    --timeout DURATION          Timeout of each request. Defaults to 20s
+    --user-agent UA             User-Agent header sent with each request
+    --concurrency N             Number of pages or posters fetched at once.
+                                Defaults to 20
+    --retries N                 Maximum number of attempts per request. Transient
+                                errors are retried with an exponential backoff.
+                                Defaults to 4
+    --rate N                    Maximum number of requests per second, shared by
+                                all the requests of the run. Defaults to 0 (no limit)
+    --burst N                   Number of requests allowed at once before --rate
+                                applies. Defaults to 1
+    --jitter DURATION           Add a random delay up to DURATION to each wait
+    --strict                    Abort when a field can't be parsed. By default, the
+                                entry is kept with the field left empty and a
+                                warning is reported
+    -k, --keep-going            Save every collection that could be backed up even
+                                if others fail. The failures are listed in
+                                report.json and the exit code is 3
+    --resume                    Record the fetched pages in a checkpoint in the
+                                output directory, and resume an interrupted or
+                                failed collection backup from it, fetching only
+                                the missing pages (legacy source)
+    --incremental               Update the previous backup of each collection,
+                                fetching only the pages with new or updated
+                                entries (legacy source, json format)
+    --full-every DURATION       With --incremental, run a full backup of the
+                                collections last fully backed up more than
+                                DURATION ago, to drop the deleted entries.
+                                Defaults to 168h
     -v, --verbose               Print verbose output
     -V, --version               Print version
 
 Examples:
     sc-backup --collection mlcdf
+    sc-backup --collection mlcdf --category films,series --filter done
     sc-backup --list https://www.senscritique.com/liste/Vu_au_cinema/363578
+    sc-backup --lists mlcdf
+    sc-backup --collection mlcdf --format letterboxd
+    sc-backup --collection mlcdf --category livres --format goodreads
+    sc-backup --collection mlcdf --format trakt
 `
 
+// importCategories are the categories supported by the import formats of
+// other websites
+var importCategories = map[string][]string{
+	"letterboxd": {"films"},
+	"goodreads":  {"livres", "bd"},
+	"storygraph": {"livres", "bd"},
+	"trakt":      {"films", "series"},
+}
+
 // Version can be set at link time to override debug.BuildInfo.Main.Version,
 // which is "(devel)" when building from within the module. See
 // golang.org/issue/29814 and golang.org/issue/29228.
@@ -51,11 +145,39 @@ func main() {
 	var (
 		isVerboseFlag  bool
 		listFlag       string
+		listsFlag      string
 		collectionFlag string
+		categoryFlag   string
+		filterFlag     string
+		reviewsFlag    bool
+		socialFlag     bool
+		enrichFlag     bool
+		imagesFlag     bool
 		outputFlag     string = "output"
+		sourceFlag     string = "legacy"
 		formatFlag     string = "json"
 		prettyFlag     bool
 		versionFlag    bool
+
+		csvDelimiterFlag     string
+		csvListSeparatorFlag string
+		csvBOMFlag           bool
+		csvColumnsFlag       string
+
+		baseURLFlag     string
+		proxyFlag       string
+		timeoutFlag     time.Duration = 20 * time.Second
+		userAgentFlag   string
+		concurrencyFlag int = backup.DefaultConcurrency
+		retriesFlag     int = retry.DefaultPolicy.MaxAttempts
+		rateFlag        float64
+		burstFlag       int = 1
+		jitterFlag      time.Duration
+		strictFlag      bool
+		keepGoingFlag   bool
+		resumeFlag      bool
+		incrementalFlag bool
+		fullEveryFlag   time.Duration = 7 * 24 * time.Hour
 	)
 
 	flag.BoolVar(&versionFlag, "version", versionFlag, "print the version")
@@ -67,18 +189,53 @@ func main() {
 	flag.StringVar(&listFlag, "list", listFlag, "Download list")
 	flag.StringVar(&listFlag, "l", listFlag, "Download list")
 
+	flag.StringVar(&listsFlag, "lists", listsFlag, "Download all the lists of a user")
+
 	flag.StringVar(&collectionFlag, "collection", collectionFlag, "Download user collection")
 	flag.StringVar(&collectionFlag, "c", collectionFlag, "Download user collection")
 
+	flag.StringVar(&categoryFlag, "category", categoryFlag, "Comma-separated categories to backup")
+	flag.StringVar(&filterFlag, "filter", filterFlag, "Comma-separated filters to backup")
+	flag.BoolVar(&reviewsFlag, "reviews", reviewsFlag, "Backup the user's reviews")
+	flag.BoolVar(&socialFlag, "social", socialFlag, "Backup the user's scouts and followers")
+	flag.BoolVar(&enrichFlag, "enrich", enrichFlag, "Add the details of their product page to the entries")
+	flag.BoolVar(&imagesFlag, "images", imagesFlag, "Download the posters of the entries")
+
 	flag.StringVar(&outputFlag, "output", outputFlag, "Output directory")
 	flag.StringVar(&outputFlag, "o", outputFlag, "Output directory")
 
-	flag.StringVar(&formatFlag, "format", formatFlag, "Output format. Either json or csv. Default to json.")
-	flag.StringVar(&formatFlag, "f", formatFlag, "Output format. Either json or csv. Default to json.")
+	flag.StringVar(&sourceFlag, "source", sourceFlag, "Source to scrape. Either legacy or graphql. Default to legacy.")
+	flag.StringVar(&sourceFlag, "s", sourceFlag, "Source to scrape. Either legacy or graphql. Default to legacy.")
+
+	flag.StringVar(&formatFlag, "format", formatFlag, "Output format. Either json, csv, letterboxd, goodreads, storygraph or trakt. Default to json.")
+	flag.StringVar(&formatFlag, "f", formatFlag, "Output format. Either json, csv, letterboxd, goodreads, storygraph or trakt. Default to json.")
 
 	flag.BoolVar(&prettyFlag, "pretty", prettyFlag, "Pretty output")
 	flag.BoolVar(&prettyFlag, "p", prettyFlag, "Pretty output")
 
+	flag.StringVar(&csvDelimiterFlag, "csv-delimiter", csvDelimiterFlag, "Delimiter of the CSV columns")
+	flag.StringVar(&csvListSeparatorFlag, "csv-list-separator", csvListSeparatorFlag, "Separator of the values of the CSV list columns")
+	flag.BoolVar(&csvBOMFlag, "csv-bom", csvBOMFlag, "Start the CSV files with a UTF-8 BOM")
+	flag.StringVar(&csvColumnsFlag, "csv-columns", csvColumnsFlag, "Comma-separated columns of the CSV files")
+
+	flag.StringVar(&baseURLFlag, "base-url", baseURLFlag, "URL of the website or GraphQL API to scrape")
+	flag.StringVar(&proxyFlag, "proxy", proxyFlag, "Proxy URL")
+	flag.DurationVar(&timeoutFlag, "timeout", timeoutFlag, "Timeout of each request")
+	flag.StringVar(&userAgentFlag, "user-agent", userAgentFlag, "User-Agent header")
+	flag.IntVar(&concurrencyFlag, "concurrency", concurrencyFlag, "Number of pages fetched at once")
+	flag.IntVar(&retriesFlag, "retries", retriesFlag, "Maximum number of attempts per request")
+	flag.Float64Var(&rateFlag, "rate", rateFlag, "Maximum number of requests per second")
+	flag.IntVar(&burstFlag, "burst", burstFlag, "Number of requests allowed at once")
+	flag.DurationVar(&jitterFlag, "jitter", jitterFlag, "Maximum random delay added to each wait")
+	flag.BoolVar(&strictFlag, "strict", strictFlag, "Abort when a field can't be parsed")
+
+	flag.BoolVar(&keepGoingFlag, "keep-going", keepGoingFlag, "Keep going when a collection fails")
+	flag.BoolVar(&keepGoingFlag, "k", keepGoingFlag, "Keep going when a collection fails")
+
+	flag.BoolVar(&resumeFlag, "resume", resumeFlag, "Resume the collection backup from the checkpoint")
+	flag.BoolVar(&incrementalFlag, "incremental", incrementalFlag, "Update the previous backup of each collection")
+	flag.DurationVar(&fullEveryFlag, "full-every", fullEveryFlag, "Run a full backup when the last one is older")
+
 	flag.Parse()
 
 	if versionFlag {
@@ -96,16 +253,66 @@ func main() {
 
 	start := time.Now()
 
-	if collectionFlag != "" && listFlag != "" {
-		log.Fatalln("error: you can't set --list and --collection at the same time")
+	modes := 0
+	for _, value := range []string{collectionFlag, listFlag, listsFlag} {
+		if value != "" {
+			modes++
+		}
+	}
+
+	if modes > 1 {
+		log.Fatalln("error: only one of --list, --lists or --collection can be set at the same time")
+	}
+
+	if modes == 0 {
+		log.Fatalln("error: at least one of --list, --lists or --collection is required")
 	}
 
-	if collectionFlag == "" && listFlag == "" {
-		log.Fatalln("error: at least one of --list or --collection is required")
+	categories, err := backup.ParseCategories(categoryFlag)
+	if err != nil {
+		log.Fatalf("error: %s", err)
 	}
 
-	if formatFlag == "csv" && prettyFlag {
-		logging.Info("warning: -p/--pretty is useless with -f/--format csv. CSV won't be prettified.")
+	filters, err := backup.ParseFilters(filterFlag)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	if sourceFlag == "legacy" {
+		for _, filter := range filters {
+			if filter == domain.StateInProgress {
+				log.Fatalln("error: the in-progress collections can only be backed up with --source graphql")
+			}
+		}
+	}
+
+	if supported, ok := importCategories[formatFlag]; ok {
+		if categoryFlag == "" {
+			categories = supported
+		}
+		isSupported := map[string]bool{}
+		for _, category := range supported {
+			isSupported[category] = true
+		}
+		for _, category := range categories {
+			if !isSupported[category] {
+				log.Fatalf("error: the %s format only supports %s", formatFlag, strings.Join(supported, " and "))
+			}
+		}
+		if reviewsFlag || socialFlag {
+			log.Fatalf("error: --reviews and --social can't be exported to %s", formatFlag)
+		}
+		if formatFlag != "letterboxd" && (listFlag != "" || listsFlag != "") {
+			log.Fatalf("error: the lists can't be exported to %s", formatFlag)
+		}
+	}
+
+	if formatFlag != "json" && formatFlag != "trakt" && prettyFlag {
+		logging.Info("warning: -p/--pretty is useless with -f/--format %s. %s output won't be prettified.", formatFlag, formatFlag)
+	}
+
+	if formatFlag != "csv" && (csvDelimiterFlag != "" || csvListSeparatorFlag != "" || csvBOMFlag || csvColumnsFlag != "") {
+		logging.Info("warning: the --csv-* options are useless without -f/--format csv.")
 	}
 
 	if isVerboseFlag {
@@ -113,27 +320,220 @@ func main() {
 	}
 
 	var back domain.Backend
-	var err error
 
 	var formatter domain.Formatter
+	var letterboxd *format.Letterboxd
 
 	switch formatFlag {
 	case "json":
 		formatter = format.NewJSON(prettyFlag)
 	case "csv":
-		formatter = &format.CSV{}
+		opts := format.CSVOptions{
+			ListSeparator: csvListSeparatorFlag,
+			BOM:           csvBOMFlag,
+		}
+		if csvDelimiterFlag != "" {
+			opts.Delimiter, err = format.ParseDelimiter(csvDelimiterFlag)
+			if err != nil {
+				log.Fatalf("error: %s", err)
+			}
+		}
+		if csvColumnsFlag != "" {
+			for _, column := range strings.Split(csvColumnsFlag, ",") {
+				opts.Columns = append(opts.Columns, strings.TrimSpace(column))
+			}
+		}
+		formatter, err = format.NewCSV(opts)
+		if err != nil {
+			log.Fatalf("error: %s", err)
+		}
+	case "letterboxd":
+		letterboxd = format.NewLetterboxd()
+		formatter = letterboxd
+	case "goodreads":
+		formatter = format.NewGoodreads()
+	case "storygraph":
+		formatter = format.NewStoryGraph()
+	case "trakt":
+		formatter = format.NewTrakt(prettyFlag)
+	default:
+		log.Fatalf("invalid format %s: it should be json|csv|letterboxd|goodreads|storygraph|trakt", formatFlag)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyFlag != "" {
+		proxyURL, err := url.Parse(proxyFlag)
+		if err != nil {
+			log.Fatalf("invalid proxy %s: %s", proxyFlag, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	limiter := ratelimit.New(rateFlag, burstFlag, jitterFlag)
+
+	logger := logging.Default()
+
+	retryPolicy := retry.DefaultPolicy
+	retryPolicy.MaxAttempts = retriesFlag
+
+	// with --resume, the pages of a collection backup are recorded in a
+	// checkpoint, so that running it again fetches only the missing ones
+	var cp *checkpoint.Checkpoint
+	if collectionFlag != "" && resumeFlag {
+		cp = checkpoint.New(filepath.Join(outputFlag, collectionFlag, checkpoint.Dirname))
+	}
+
+	if resumeFlag && sourceFlag != "legacy" {
+		logging.Info("warning: --resume is only supported by the legacy source")
+	}
+
+	if incrementalFlag && (sourceFlag != "legacy" || formatFlag != "json") {
+		logging.Info("warning: --incremental is only supported by the legacy source with the json format, running a full backup")
+		incrementalFlag = false
+	}
+
+	var source domain.Source
+	var legacy *backup.Client
+
+	switch sourceFlag {
+	case "legacy":
+		options := []backup.Option{
+			backup.WithTransport(transport),
+			backup.WithLimiter(limiter),
+			backup.WithTimeout(timeoutFlag),
+			backup.WithUserAgent(userAgentFlag),
+			backup.WithConcurrency(concurrencyFlag),
+			backup.WithLogger(logger),
+			backup.WithRetryPolicy(retryPolicy),
+			backup.WithStrictParsing(strictFlag),
+			backup.WithCheckpoint(cp),
+		}
+		if baseURLFlag != "" {
+			options = append(options, backup.WithBaseURL(strings.TrimSuffix(baseURLFlag, "/")))
+		}
+		legacy = backup.New(options...)
+		source = legacy
+	case "graphql":
+		endpoint := graphql.Endpoint
+		if baseURLFlag != "" {
+			endpoint = baseURLFlag
+		}
+		source = graphql.New(endpoint,
+			graphql.WithTransport(transport),
+			graphql.WithLimiter(limiter),
+			graphql.WithTimeout(timeoutFlag),
+			graphql.WithUserAgent(userAgentFlag),
+			graphql.WithConcurrency(concurrencyFlag),
+			graphql.WithLogger(logger),
+			graphql.WithRetryPolicy(retryPolicy),
+		)
 	default:
-		log.Fatalf("invalid format %s: it should be json|csv|html", formatFlag)
+		log.Fatalf("invalid source %s: it should be legacy|graphql", sourceFlag)
+	}
+
+	// the first Ctrl-C stops the new fetches and waits for the running ones,
+	// the second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	var summary *backup.Summary
+
+	// the posters are shared by the collections and the lists of every user
+	var covers *backup.Covers
+	if imagesFlag {
+		covers = backup.NewCovers(backend.NewFS(outputFlag, formatter, logger), concurrencyFlag)
 	}
 
 	if collectionFlag != "" {
-		back = backend.NewFS(filepath.Join(outputFlag, collectionFlag), formatter)
-		err = backup.Collection(collectionFlag, back)
+		var products *cache.Cache
+		if enrichFlag {
+			products, err = cache.Load(filepath.Join(outputFlag, cache.Filename))
+			if err != nil {
+				log.Fatalf("error: failed to load the product cache: %s", err)
+			}
+		}
+
+		back = backend.NewFS(filepath.Join(outputFlag, collectionFlag), formatter, logger)
+		summary, err = backup.Collection(ctx, source, collectionFlag, back, backup.Options{
+			KeepGoing:   keepGoingFlag,
+			Incremental: incrementalFlag,
+			FullEvery:   fullEveryFlag,
+			Categories:  categories,
+			Filters:     filters,
+			Reviews:     reviewsFlag,
+			Social:      socialFlag,
+			Enrich:      enrichFlag,
+			Products:    products,
+			Covers:      covers,
+			Logger:      logger,
+		})
+		if err == nil {
+			if err := cp.Remove(); err != nil {
+				logging.Info("warning: failed to remove the checkpoint: %s", err)
+			}
+		}
 	}
 
 	if listFlag != "" {
-		back = backend.NewFS(outputFlag, formatter)
-		err = backup.List(listFlag, back)
+		back = backend.NewFS(outputFlag, formatter, logger)
+		err = backup.List(ctx, source, listFlag, back, backup.Options{
+			Covers: covers,
+			Logger: logger,
+		})
+	}
+
+	if listsFlag != "" {
+		back = backend.NewFS(filepath.Join(outputFlag, listsFlag, "lists"), formatter, logger)
+		summary, err = backup.Lists(ctx, source, listsFlag, back, backup.Options{
+			KeepGoing: keepGoingFlag,
+			Covers:    covers,
+			Logger:    logger,
+		})
+	}
+
+	if requests, waited := limiter.Stats(); rateFlag > 0 {
+		logging.Debug("%d requests waited %s in total for the rate limiter", requests, waited.Round(time.Millisecond))
+	}
+
+	if legacy != nil {
+		if parseErrors := legacy.ParseErrors(); len(parseErrors) > 0 {
+			logging.Info("warning: %d fields could not be parsed and were left empty:", len(parseErrors))
+			for _, e := range parseErrors {
+				logging.Info("    %s", e)
+			}
+		}
+	}
+
+	if letterboxd != nil {
+		if reports := letterboxd.Reports(); len(reports) > 0 {
+			logging.Info("warning: %d films may not be imported as is by Letterboxd:", len(reports))
+			for _, report := range reports {
+				logging.Info("    %s", report)
+			}
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		logging.Info("Interrupted after %s", time.Since(start).Round(time.Millisecond).String())
+	}
+
+	if err != nil && summary != nil {
+		if len(summary.Saved) > 0 {
+			logging.Info("Saved: %s", strings.Join(summary.Saved, ", "))
+		}
+		logging.Info("Not saved: %s", strings.Join(summary.Missing, ", "))
+	}
+
+	if errors.Is(err, backup.ErrIncomplete) {
+		// the report is always JSON so that it can be read by scripts
+		if err := backend.NewFS(back.Location(), format.NewJSON(true), logger).Save(summary); err != nil {
+			log.Fatalf("error: failed to save the report: %s", err)
+		}
+		logging.Info("error: %s, see %s", err, filepath.Join(back.Location(), summary.Slug()+".json"))
+		os.Exit(3)
 	}
 
 	if err != nil {